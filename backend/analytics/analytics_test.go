@@ -0,0 +1,179 @@
+package analytics
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ProlificLabs/captrivia/game"
+)
+
+// batchRecorder is a store that remembers the size of each batch it was given, and can be made to hold up writes.
+type batchRecorder struct {
+	mutex   sync.Mutex
+	batches [][]game.AnalyticsEvent
+	hold    chan struct{}
+}
+
+func (b *batchRecorder) WriteEvents(ctx context.Context, events []game.AnalyticsEvent) error {
+	if b.hold != nil {
+		<-b.hold
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.batches = append(b.batches, events)
+	return nil
+}
+
+func (b *batchRecorder) batchSizes() []int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	sizes := make([]int, len(b.batches))
+	for i, batch := range b.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func TestAsyncSinkBatchesBySize(t *testing.T) {
+	store := &batchRecorder{}
+	sink := NewAsyncSink(store, 100, 10, time.Hour)
+	for i := 0; i < 25; i++ {
+		sink.Record(game.AnalyticsEvent{Type: game.AnalyticsAnswerSubmitted})
+	}
+	sink.Close() // writes out the last partial batch
+
+	sizes := store.batchSizes()
+	if len(sizes) != 3 || sizes[0] != 10 || sizes[1] != 10 || sizes[2] != 5 {
+		t.Fatalf("expected batches of 10, 10 and 5, got %v", sizes)
+	}
+}
+
+func TestAsyncSinkFlushesAfterInterval(t *testing.T) {
+	store := &batchRecorder{}
+	sink := NewAsyncSink(store, 100, 10, 10*time.Millisecond)
+	defer sink.Close()
+	sink.Record(game.AnalyticsEvent{Type: game.AnalyticsLobbyCreated})
+
+	deadline := time.Now().Add(time.Second)
+	for len(store.batchSizes()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a partial batch to be written after the flush interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAsyncSinkNeverBlocksWhenStoreIsStuck(t *testing.T) {
+	store := &batchRecorder{hold: make(chan struct{})}
+	sink := NewAsyncSink(store, 5, 1, time.Hour)
+
+	recorded := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			sink.Record(game.AnalyticsEvent{Type: game.AnalyticsAnswerSubmitted})
+		}
+		close(recorded)
+	}()
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		t.Fatalf("recording blocked on a stuck store")
+	}
+	// one event is stuck being written, five are queued and the rest had nowhere to go.
+	if sink.Dropped() < 90 {
+		t.Errorf("expected most events to be dropped, got %d", sink.Dropped())
+	}
+	close(store.hold)
+	sink.Close()
+}
+
+// the postgres store is only tested when there is a database to test against, eg
+// ANALYTICS_TEST_DSN="host=localhost user=postgres password=postgres dbname=captrivia sslmode=disable"
+func TestPostgresStore(t *testing.T) {
+	dsn := os.Getenv("ANALYTICS_TEST_DSN")
+	if dsn == "" {
+		t.Skip("ANALYTICS_TEST_DSN is not set")
+	}
+	store, err := OpenPostgres(dsn)
+	if err != nil {
+		t.Fatalf("failed to open postgres: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	// migrating twice should be harmless.
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate a second time: %v", err)
+	}
+
+	lobbyID := "test-" + time.Now().Format(time.RFC3339Nano)
+	events := []game.AnalyticsEvent{
+		{Type: game.AnalyticsAnswerSubmitted, LobbyID: lobbyID, PlayerID: "p1", QuestionID: "q1", AnswerIndex: 2, Correct: true, Points: 10, LatencyMs: 1234, OccurredAt: time.Now()},
+		{Type: game.AnalyticsGameEnded, LobbyID: lobbyID, PlayerID: "p1", Points: 10, Won: true, OccurredAt: time.Now()},
+	}
+	if err := store.WriteEvents(ctx, events); err != nil {
+		t.Fatalf("failed to write events: %v", err)
+	}
+	defer store.DB().Exec(`DELETE FROM analytics_events WHERE lobby_id = $1`, lobbyID)
+
+	var count int
+	if err := store.DB().QueryRow(`SELECT count(*) FROM analytics_events WHERE lobby_id = $1`, lobbyID).Scan(&count); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if count != len(events) {
+		t.Errorf("expected %d events written, found %d", len(events), count)
+	}
+}
+
+func TestMemoryStoreStats(t *testing.T) {
+	store := NewMemoryStore()
+	answer := func(playerID, questionID string, answerIndex int, correct bool, latencyMs int64) {
+		store.Record(game.AnalyticsEvent{Type: game.AnalyticsAnswerSubmitted, LobbyID: "lobby1", PlayerID: playerID, QuestionID: questionID, AnswerIndex: answerIndex, Correct: correct, LatencyMs: latencyMs})
+	}
+	answer("p1", "easy", 0, true, 1000)
+	answer("p2", "easy", 0, true, 3000)
+	answer("p1", "hard", 2, false, 0)
+	answer("p2", "hard", 2, false, 0)
+	answer("p3", "hard", 1, false, 0)
+	answer("p3", "hard", 0, true, 5000)
+	store.Record(game.AnalyticsEvent{Type: game.AnalyticsGameEnded, LobbyID: "lobby1", PlayerID: "p1", Won: true})
+	store.Record(game.AnalyticsEvent{Type: game.AnalyticsGameEnded, LobbyID: "lobby1", PlayerID: "p2"})
+
+	questions, err := store.QuestionStats(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(questions) != 2 || questions[0].QuestionID != "hard" || questions[1].QuestionID != "easy" {
+		t.Fatalf("expected the hard question first, got %+v", questions)
+	}
+	hard, easy := questions[0], questions[1]
+	if hard.Answers != 4 || hard.CorrectCount != 1 || hard.CorrectRate != 0.25 || hard.AverageTimeToCorrectMs != 5000 {
+		t.Errorf("unexpected hard question stats %+v", hard)
+	}
+	if hard.MostCommonWrongAnswer == nil || *hard.MostCommonWrongAnswer != 2 || hard.MostCommonWrongCount != 2 {
+		t.Errorf("expected option 2 to be the most common wrong answer, got %+v", hard)
+	}
+	if easy.CorrectRate != 1 || easy.AverageTimeToCorrectMs != 2000 || easy.MostCommonWrongAnswer != nil {
+		t.Errorf("unexpected easy question stats %+v", easy)
+	}
+
+	p1, err := store.PlayerStats(context.Background(), "lobby1", "p1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p1.Answers != 2 || p1.Accuracy != 0.5 || p1.Games != 1 || p1.WinRate != 1 {
+		t.Errorf("unexpected p1 stats %+v", p1)
+	}
+	if _, err := store.PlayerStats(context.Background(), "lobby1", "nobody"); err != ErrNoPlayerStats {
+		t.Errorf("expected ErrNoPlayerStats, got %v", err)
+	}
+	if _, err := store.PlayerStats(context.Background(), "lobby2", "p1"); err != ErrNoPlayerStats {
+		t.Errorf("expected no stats for a player in a lobby they were not in, got %v", err)
+	}
+}
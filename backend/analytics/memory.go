@@ -0,0 +1,40 @@
+package analytics
+
+import (
+	"context"
+	"github.com/ProlificLabs/captrivia/game"
+	"sync"
+)
+
+// MemoryStore keeps analytics events in memory. it is a Store, and also a game.AnalyticsSink on its own for tests
+// that want to see events as soon as they happen.
+type MemoryStore struct {
+	mutex  sync.Mutex
+	events []game.AnalyticsEvent
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (m *MemoryStore) Record(event game.AnalyticsEvent) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.events = append(m.events, event)
+}
+
+func (m *MemoryStore) WriteEvents(ctx context.Context, events []game.AnalyticsEvent) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.events = append(m.events, events...)
+	return nil
+}
+
+// Events returns a copy of everything recorded so far, oldest first.
+func (m *MemoryStore) Events() []game.AnalyticsEvent {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	events := make([]game.AnalyticsEvent, len(m.events))
+	copy(events, m.events)
+	return events
+}
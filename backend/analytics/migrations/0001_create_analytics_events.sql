@@ -0,0 +1,16 @@
+CREATE TABLE analytics_events (
+    id           BIGSERIAL PRIMARY KEY,
+    event_type   TEXT        NOT NULL,
+    lobby_id     TEXT        NOT NULL,
+    player_id    TEXT        NOT NULL DEFAULT '',
+    question_id  TEXT        NOT NULL DEFAULT '',
+    answer_index INTEGER     NOT NULL DEFAULT 0,
+    correct      BOOLEAN     NOT NULL DEFAULT FALSE,
+    points       INTEGER     NOT NULL DEFAULT 0,
+    latency_ms   BIGINT      NOT NULL DEFAULT 0,
+    won          BOOLEAN     NOT NULL DEFAULT FALSE,
+    occurred_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX analytics_events_question_idx ON analytics_events (question_id) WHERE event_type = 'answer_submitted';
+CREATE INDEX analytics_events_player_idx ON analytics_events (player_id, event_type);
@@ -0,0 +1,219 @@
+package analytics
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/ProlificLabs/captrivia/game"
+	_ "github.com/lib/pq" // registers the postgres driver
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrations are applied in filename order, each one once. the number before the first underscore is its version.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// PostgresStore keeps analytics events in postgres.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// OpenPostgres connects to postgres. the connection is made lazily, so this only fails on a bad dsn.
+func OpenPostgres(dsn string) (*PostgresStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+func (p *PostgresStore) DB() *sql.DB {
+	return p.db
+}
+
+func (p *PostgresStore) Close() error {
+	return p.db.Close()
+}
+
+// Migrate brings the schema up to date, applying any migrations that have not been applied yet.
+func (p *PostgresStore) Migrate(ctx context.Context) error {
+	if _, err := p.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	files, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version, err := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if err != nil {
+			return fmt.Errorf("migration %s has no version number: %w", name, err)
+		}
+		if err := p.applyMigration(ctx, version, name); err != nil {
+			return fmt.Errorf("migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p *PostgresStore) applyMigration(ctx context.Context, version int, name string) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// serialize servers starting up at the same time so only one of them applies each migration.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE schema_migrations IN EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+	var applied bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied {
+		return nil
+	}
+
+	statements, err := migrations.ReadFile("migrations/" + name)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, string(statements)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+		return err
+	}
+	log.Printf("applied analytics migration %s", name)
+	return tx.Commit()
+}
+
+// WriteEvents inserts a batch of events in a single statement.
+func (p *PostgresStore) WriteEvents(ctx context.Context, events []game.AnalyticsEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	const columns = 10
+	var query strings.Builder
+	query.WriteString(`INSERT INTO analytics_events (event_type, lobby_id, player_id, question_id, answer_index, correct, points, latency_ms, won, occurred_at) VALUES `)
+	args := make([]interface{}, 0, len(events)*columns)
+	for i, event := range events {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteString("(")
+		for c := 1; c <= columns; c++ {
+			if c > 1 {
+				query.WriteString(", ")
+			}
+			query.WriteString("$" + strconv.Itoa(i*columns+c))
+		}
+		query.WriteString(")")
+		args = append(args, string(event.Type), event.LobbyID, event.PlayerID, event.QuestionID, event.AnswerIndex, event.Correct, event.Points, event.LatencyMs, event.Won, event.OccurredAt)
+	}
+
+	_, err := p.db.ExecContext(ctx, query.String(), args...)
+	return err
+}
+
+func (p *PostgresStore) QuestionStats(ctx context.Context) ([]QuestionStats, error) {
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT question_id,
+		       count(*),
+		       count(*) FILTER (WHERE correct),
+		       coalesce(avg(latency_ms) FILTER (WHERE correct), 0)
+		FROM analytics_events
+		WHERE event_type = $1
+		GROUP BY question_id`, string(game.AnalyticsAnswerSubmitted))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byQuestion := make(map[string]*QuestionStats)
+	var stats []QuestionStats
+	for rows.Next() {
+		var s QuestionStats
+		if err := rows.Scan(&s.QuestionID, &s.Answers, &s.CorrectCount, &s.AverageTimeToCorrectMs); err != nil {
+			return nil, err
+		}
+		s.CorrectRate = ratio(s.CorrectCount, s.Answers)
+		stats = append(stats, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range stats {
+		byQuestion[stats[i].QuestionID] = &stats[i]
+	}
+
+	// the most picked wrong option for each question, ties going to the lower option index.
+	// answers that are not one option are recorded with an index of -1 and left out.
+	rows, err = p.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (question_id) question_id, answer_index, count(*) AS picks
+		FROM analytics_events
+		WHERE event_type = $1 AND NOT correct AND answer_index >= 0
+		GROUP BY question_id, answer_index
+		ORDER BY question_id, picks DESC, answer_index`, string(game.AnalyticsAnswerSubmitted))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var questionID string
+		var answer, count int
+		if err := rows.Scan(&questionID, &answer, &count); err != nil {
+			return nil, err
+		}
+		if s, ok := byQuestion[questionID]; ok {
+			s.MostCommonWrongAnswer = &answer
+			s.MostCommonWrongCount = count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sortQuestionStats(stats)
+	return stats, nil
+}
+
+func (p *PostgresStore) PlayerStats(ctx context.Context, lobbyID, playerID string) (PlayerStats, error) {
+	stats := PlayerStats{LobbyID: lobbyID, PlayerID: playerID}
+	var events int
+	err := p.db.QueryRowContext(ctx, `
+		SELECT count(*),
+		       count(*) FILTER (WHERE event_type = $3),
+		       count(*) FILTER (WHERE event_type = $3 AND correct),
+		       count(*) FILTER (WHERE event_type = $4),
+		       count(*) FILTER (WHERE event_type = $4 AND won)
+		FROM analytics_events
+		WHERE player_id = $1 AND lobby_id = $2`, playerID, lobbyID, string(game.AnalyticsAnswerSubmitted), string(game.AnalyticsGameEnded),
+	).Scan(&events, &stats.Answers, &stats.CorrectCount, &stats.Games, &stats.Wins)
+	if err != nil {
+		return stats, err
+	}
+	if events == 0 {
+		return stats, ErrNoPlayerStats
+	}
+	stats.Accuracy = ratio(stats.CorrectCount, stats.Answers)
+	stats.WinRate = ratio(stats.Wins, stats.Games)
+	return stats, nil
+}
@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"context"
+	"github.com/ProlificLabs/captrivia/game"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store is somewhere analytics events are kept, like postgres.
+type Store interface {
+	WriteEvents(ctx context.Context, events []game.AnalyticsEvent) error
+}
+
+// AsyncSink is a game.AnalyticsSink that writes to a Store in batches from a background goroutine,
+// so the game never waits on the database. if the store can't keep up and the queue fills, events are dropped and counted.
+type AsyncSink struct {
+	store         Store
+	queue         chan game.AnalyticsEvent
+	batchSize     int
+	flushInterval time.Duration
+	dropped       atomic.Uint64
+	failed        atomic.Uint64
+	closeOnce     sync.Once
+	done          chan struct{}
+}
+
+// NewAsyncSink starts writing to store. a batch is written once it has batchSize events, or flushInterval after its first event.
+func NewAsyncSink(store Store, queueSize, batchSize int, flushInterval time.Duration) *AsyncSink {
+	s := &AsyncSink{
+		store:         store,
+		queue:         make(chan game.AnalyticsEvent, queueSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Record queues an event to be written. it never blocks.
+func (s *AsyncSink) Record(event game.AnalyticsEvent) {
+	select {
+	case s.queue <- event:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Dropped is how many events were thrown away because the queue was full.
+func (s *AsyncSink) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Failed is how many events were lost because the store returned an error writing them.
+func (s *AsyncSink) Failed() uint64 {
+	return s.failed.Load()
+}
+
+// Close writes out whatever is still queued and stops the background goroutine. nothing may be recorded after closing.
+func (s *AsyncSink) Close() {
+	s.closeOnce.Do(func() {
+		close(s.queue)
+		<-s.done
+	})
+}
+
+func (s *AsyncSink) run() {
+	defer close(s.done)
+
+	batch := make([]game.AnalyticsEvent, 0, s.batchSize)
+	timer := time.NewTimer(s.flushInterval)
+	timer.Stop()
+	flush := func() {
+		timer.Stop()
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.store.WriteEvents(context.Background(), batch); err != nil {
+			log.Printf("failed to write %d analytics events: %v", len(batch), err)
+			s.failed.Add(uint64(len(batch)))
+		}
+		batch = make([]game.AnalyticsEvent, 0, s.batchSize)
+	}
+
+	for {
+		select {
+		case event, ok := <-s.queue:
+			if !ok {
+				flush()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(s.flushInterval)
+			}
+			batch = append(batch, event)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-timer.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"github.com/ProlificLabs/captrivia/game"
+	"sort"
+)
+
+var ErrNoPlayerStats = errors.New("no analytics recorded for this player in this lobby")
+
+// QuestionStats is how players have done on one question, across every game it came up in.
+type QuestionStats struct {
+	QuestionID             string  `json:"questionId"`
+	Answers                int     `json:"answers"`
+	CorrectCount           int     `json:"correctCount"`
+	CorrectRate            float64 `json:"correctRate"`            // 0 to 1
+	AverageTimeToCorrectMs float64 `json:"averageTimeToCorrectMs"` // 0 if nobody has got it right yet
+	MostCommonWrongAnswer  *int    `json:"mostCommonWrongAnswer"`  // option index, nil if nobody has got it wrong yet
+	MostCommonWrongCount   int     `json:"mostCommonWrongCount"`
+}
+
+// PlayerStats is how one player has done in one lobby, over every game played in it.
+// players get a new id in each lobby they join, and names can repeat across lobbies, so there is nothing to total them up by across lobbies.
+type PlayerStats struct {
+	LobbyID      string  `json:"lobbyId"`
+	PlayerID     string  `json:"playerId"`
+	Answers      int     `json:"answers"`
+	CorrectCount int     `json:"correctCount"`
+	Accuracy     float64 `json:"accuracy"` // 0 to 1
+	Games        int     `json:"games"`
+	Wins         int     `json:"wins"`
+	WinRate      float64 `json:"winRate"` // 0 to 1
+}
+
+// Querier answers questions about the analytics events that have been recorded.
+type Querier interface {
+	QuestionStats(ctx context.Context) ([]QuestionStats, error)
+	PlayerStats(ctx context.Context, lobbyID, playerID string) (PlayerStats, error)
+}
+
+// sortQuestionStats puts the questions players find hardest first, which is what the content team is usually looking for.
+func sortQuestionStats(stats []QuestionStats) {
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].CorrectRate != stats[j].CorrectRate {
+			return stats[i].CorrectRate < stats[j].CorrectRate
+		}
+		return stats[i].QuestionID < stats[j].QuestionID
+	})
+}
+
+func ratio(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
+
+func (m *MemoryStore) QuestionStats(ctx context.Context) ([]QuestionStats, error) {
+	type tally struct {
+		answers, correct int
+		timeToCorrectMs  int64
+		wrongAnswers     map[int]int
+	}
+	tallies := make(map[string]*tally)
+	for _, event := range m.Events() {
+		if event.Type != game.AnalyticsAnswerSubmitted {
+			continue
+		}
+		t, ok := tallies[event.QuestionID]
+		if !ok {
+			t = &tally{wrongAnswers: make(map[int]int)}
+			tallies[event.QuestionID] = t
+		}
+		t.answers++
+		if event.Correct {
+			t.correct++
+			t.timeToCorrectMs += event.LatencyMs
+		} else if event.AnswerIndex >= 0 { // multi-select and numeric answers are not one option, so they have no most common wrong one
+			t.wrongAnswers[event.AnswerIndex]++
+		}
+	}
+
+	stats := make([]QuestionStats, 0, len(tallies))
+	for questionID, t := range tallies {
+		s := QuestionStats{
+			QuestionID:   questionID,
+			Answers:      t.answers,
+			CorrectCount: t.correct,
+			CorrectRate:  ratio(t.correct, t.answers),
+		}
+		if t.correct > 0 {
+			s.AverageTimeToCorrectMs = float64(t.timeToCorrectMs) / float64(t.correct)
+		}
+		for answer, count := range t.wrongAnswers {
+			// ties go to the lower option index, same as the postgres query.
+			if count > s.MostCommonWrongCount || (count == s.MostCommonWrongCount && answer < *s.MostCommonWrongAnswer) {
+				answer := answer
+				s.MostCommonWrongAnswer = &answer
+				s.MostCommonWrongCount = count
+			}
+		}
+		stats = append(stats, s)
+	}
+	sortQuestionStats(stats)
+	return stats, nil
+}
+
+func (m *MemoryStore) PlayerStats(ctx context.Context, lobbyID, playerID string) (PlayerStats, error) {
+	stats := PlayerStats{LobbyID: lobbyID, PlayerID: playerID}
+	seen := false
+	for _, event := range m.Events() {
+		if event.LobbyID != lobbyID || event.PlayerID != playerID {
+			continue
+		}
+		seen = true
+		switch event.Type {
+		case game.AnalyticsAnswerSubmitted:
+			stats.Answers++
+			if event.Correct {
+				stats.CorrectCount++
+			}
+		case game.AnalyticsGameEnded:
+			stats.Games++
+			if event.Won {
+				stats.Wins++
+			}
+		}
+	}
+	if !seen {
+		return stats, ErrNoPlayerStats
+	}
+	stats.Accuracy = ratio(stats.CorrectCount, stats.Answers)
+	stats.WinRate = ratio(stats.Wins, stats.Games)
+	return stats, nil
+}
@@ -0,0 +1,62 @@
+package game
+
+import "time"
+
+// AnalyticsEventType is what happened, for the analytics sink.
+type AnalyticsEventType string
+
+const (
+	AnalyticsLobbyCreated    AnalyticsEventType = "lobby_created"
+	AnalyticsGameStarted     AnalyticsEventType = "game_started"
+	AnalyticsAnswerSubmitted AnalyticsEventType = "answer_submitted"
+	AnalyticsQuestionSkipped AnalyticsEventType = "question_skipped" // nobody got it right, either it timed out or everyone answered wrong
+	AnalyticsGameEnded       AnalyticsEventType = "game_ended"       // one per player, with their final score and whether they won
+)
+
+// AnalyticsEvent is one row of analytics. which fields are filled in depends on the type.
+type AnalyticsEvent struct {
+	Type        AnalyticsEventType `json:"type"`
+	LobbyID     string             `json:"lobbyId"`
+	PlayerID    string             `json:"playerId,omitempty"` // public player id, never the session id
+	QuestionID  string             `json:"questionId,omitempty"`
+	AnswerIndex int                `json:"answerIndex"` // -1 for answers that are not a single option, like multi-select and numeric ones
+	Correct     bool               `json:"correct"`
+	Points      int                `json:"points"` // points awarded for an answer, or the final score at the end of the game
+	LatencyMs   int64              `json:"latencyMs"`
+	Won         bool               `json:"won"`
+	OccurredAt  time.Time          `json:"occurredAt"`
+}
+
+// AnalyticsSink receives analytics events from the lobbies.
+// Record is called while holding the lobby mutex, so it must not block or do any I/O itself.
+type AnalyticsSink interface {
+	Record(event AnalyticsEvent)
+}
+
+// noAnalytics is the sink for when analytics are not configured.
+type noAnalytics struct{}
+
+func (noAnalytics) Record(AnalyticsEvent) {}
+
+// record fills in the lobby and time and passes the event along to the sink. must be called while holding the lobby mutex.
+func (g *GameLobby) record(event AnalyticsEvent) {
+	event.LobbyID = g.ID
+	event.OccurredAt = time.Now()
+	g.analytics.Record(event)
+}
+
+// recordGameEnded records each player's final result. must be called while holding the lobby mutex.
+func (g *GameLobby) recordGameEnded(status GameStatusResult) {
+	for _, player := range g.contestants() {
+		won := false
+		for _, winner := range status.Winners {
+			won = won || winner.ID == player.ID
+		}
+		g.record(AnalyticsEvent{
+			Type:     AnalyticsGameEnded,
+			PlayerID: player.ID,
+			Points:   player.Score,
+			Won:      won,
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AnswerPolicy is what happens after a player answers a question wrong.
+type AnswerPolicy string
+
+const (
+	// OneGuess is the original rule, a wrong answer is the player's only go at the question.
+	OneGuess AnswerPolicy = "one-guess"
+	// RetryAfterCooldown lets the player have another go once the cooldown has passed, until they are locked out.
+	RetryAfterCooldown AnswerPolicy = "retry"
+)
+
+// AnswerRules are a lobby's settings for wrong answers. the zero value is the original behaviour, one guess and no penalty.
+type AnswerRules struct {
+	WrongAnswerPenalty int          `json:"wrongAnswerPenalty,omitempty"` // points taken off for each wrong answer
+	AnswerPolicy       AnswerPolicy `json:"answerPolicy,omitempty"`       // blank means OneGuess
+	RetryCooldownMs    int          `json:"retryCooldownMs,omitempty"`    // with RetryAfterCooldown, how long before a player can try again
+	LockoutAfterWrong  int          `json:"lockoutAfterWrong,omitempty"`  // with RetryAfterCooldown, wrong answers over the whole game before the player is locked out of the rest of it, 0 for no limit
+}
+
+// AnswerResult is what happened to a submitted answer, for the player who submitted it.
+type AnswerResult struct {
+	Accepted     bool    `json:"accepted"`         // false when the answer was refused and nothing changed
+	Correct      bool    `json:"correct"`          // in RoundEveryone this stays false until the reveal
+	Credit       float64 `json:"credit,omitempty"` // share of the points earned by a partly right answer, which is not Correct
+	Points       int     `json:"points"`           // negative for a wrong answer penalty
+	Explanation  string  `json:"explanation,omitempty"`
+	Score        int     `json:"score"`
+	WrongAnswers int     `json:"wrongAnswers"`           // the player's wrong answers this game so far
+	RetryAfterMs int64   `json:"retryAfterMs,omitempty"` // how long until the player may answer this question again
+	LockedOut    bool    `json:"lockedOut"`              // the player has no more goes at this question, or at any question once LockoutAfterWrong is reached
+}
+
+func (r AnswerRules) policy() AnswerPolicy {
+	if r.AnswerPolicy == "" {
+		return OneGuess
+	}
+	return r.AnswerPolicy
+}
+
+// Validate checks the rules make sense on their own and with the round mode they will be played in.
+func (r AnswerRules) Validate(roundMode RoundMode) error {
+	if r.WrongAnswerPenalty < 0 {
+		return errors.New("wrong answer penalty is the number of points to take off, so it cannot be negative")
+	}
+	if r.RetryCooldownMs < 0 || r.LockoutAfterWrong < 0 {
+		return errors.New("retry cooldown and lockout cannot be negative")
+	}
+	switch r.policy() {
+	case OneGuess:
+		if r.RetryCooldownMs != 0 || r.LockoutAfterWrong != 0 {
+			return fmt.Errorf("retry cooldown and lockout only apply to the %q answer policy", RetryAfterCooldown)
+		}
+	case RetryAfterCooldown:
+		if roundMode == RoundEveryone {
+			return fmt.Errorf("the %q answer policy does not work with the %q round mode, where answers are locked in", RetryAfterCooldown, RoundEveryone)
+		}
+	default:
+		return fmt.Errorf("unknown answer policy %q, expected %q or %q", r.AnswerPolicy, OneGuess, RetryAfterCooldown)
+	}
+	return nil
+}
+
+// penalize takes the wrong answer penalty off the player, returning what was taken off or nil if the lobby has no penalty.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) penalize(player *Player, questionID string) *ScoreAward {
+	penalty := g.AnswerRules.WrongAnswerPenalty
+	if penalty == 0 {
+		return nil
+	}
+	player.Score -= penalty
+	return &ScoreAward{
+		PlayerID:    player.ID,
+		QuestionID:  questionID,
+		Points:      -penalty,
+		Explanation: fmt.Sprintf("wrong answer: -%d points", penalty),
+	}
+}
+
+// wrongAnswer applies the answer rules to a wrong answer in RoundRace, working out whether the player can try again.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) wrongAnswer(player *Player, questionID string) (AnswerResult, *ScoreAward) {
+	penalty := g.penalize(player, questionID)
+	player.wrongAnswers++
+	result := AnswerResult{
+		Accepted:     true,
+		Explanation:  "wrong answer",
+		Score:        player.Score,
+		WrongAnswers: player.wrongAnswers,
+	}
+	if penalty != nil {
+		result.Points = penalty.Points
+		result.Explanation = penalty.Explanation
+	}
+
+	if g.AnswerRules.policy() == OneGuess || g.lockedOut(player) {
+		result.LockedOut = true
+		return result, penalty
+	}
+	cooldown := time.Duration(g.AnswerRules.RetryCooldownMs) * time.Millisecond
+	player.retryAt = time.Now().Add(cooldown)
+	result.RetryAfterMs = cooldown.Milliseconds()
+	return result, penalty
+}
+
+// lockedOut is whether the player has had as many wrong answers this game as the lobby allows, which is the end of the game for them.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) lockedOut(player *Player) bool {
+	return g.AnswerRules.LockoutAfterWrong > 0 && player.wrongAnswers >= g.AnswerRules.LockoutAfterWrong
+}
+
+// everyoneLockedOut is whether no player can answer anything for the rest of the game. must be called while holding the lobby mutex.
+func (g *GameLobby) everyoneLockedOut() bool {
+	contestants := g.contestants()
+	for _, player := range contestants {
+		if !g.lockedOut(player) {
+			return false
+		}
+	}
+	return len(contestants) > 0
+}
+
+// startAttempt clears the player's cooldown when they move on to a new question. their wrong answers count for the whole game.
+func (p *Player) startAttempt(questionID string) {
+	if p.attemptQuestionID == questionID {
+		return
+	}
+	p.attemptQuestionID = questionID
+	p.retryAt = time.Time{}
+}
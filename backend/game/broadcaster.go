@@ -0,0 +1,186 @@
+package game
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// subscriberQueueSize is how many undelivered events a subscriber can have waiting before the overflow policy kicks in.
+// a whole game is a few events per question, so a connected client should never get anywhere near this.
+const subscriberQueueSize = 64
+
+// OverflowPolicy decides what happens when an event can't be queued for a subscriber because their queue is full.
+// droppable events (roster, scores, chat) are always just dropped, and only get up to half the queue so there is room left for the rest.
+type OverflowPolicy int
+
+const (
+	// OverflowDisconnect closes the subscription, the client can reconnect and replay what it missed from the event log.
+	OverflowDisconnect OverflowPolicy = iota
+	// OverflowDrop drops the event and keeps the subscription, for consumers that can live with gaps.
+	OverflowDrop
+)
+
+// BroadcastMetrics counts what a broadcaster has done since it was created.
+type BroadcastMetrics struct {
+	Subscribers  int    `json:"subscribers"`
+	Published    uint64 `json:"published"`
+	Delivered    uint64 `json:"delivered"`
+	Dropped      uint64 `json:"dropped"`
+	Disconnected uint64 `json:"disconnected"`
+}
+
+func (m *BroadcastMetrics) add(other BroadcastMetrics) {
+	m.Subscribers += other.Subscribers
+	m.Published += other.Published
+	m.Delivered += other.Delivered
+	m.Dropped += other.Dropped
+	m.Disconnected += other.Disconnected
+}
+
+// Subscription is one consumer's bounded queue of events from a broadcaster.
+type Subscription struct {
+	events    chan *Event
+	policy    OverflowPolicy
+	spectator bool // only spectators get the events meant for them, see EventType.spectatorOnly
+	dropped   atomic.Uint64
+}
+
+// Events is where the subscriber reads from. it is closed when the subscription ends, either by unsubscribing or by overflowing.
+func (s *Subscription) Events() <-chan *Event {
+	return s.events
+}
+
+// Dropped is how many events this subscriber has missed because its queue was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Broadcaster fans events out to its subscribers without ever waiting on any of them.
+// a subscriber that is not keeping up loses events (or its subscription) rather than holding up everyone else.
+type Broadcaster struct {
+	mutex        sync.Mutex
+	subscribers  map[*Subscription]struct{}
+	published    uint64
+	delivered    uint64
+	dropped      uint64
+	disconnected uint64
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func (b *Broadcaster) Subscribe(queueSize int, policy OverflowPolicy) *Subscription {
+	return b.subscribe(queueSize, policy, false)
+}
+
+// SubscribeSpectator is Subscribe for someone who is only watching, who also gets the events that are just for spectators.
+func (b *Broadcaster) SubscribeSpectator(queueSize int, policy OverflowPolicy) *Subscription {
+	return b.subscribe(queueSize, policy, true)
+}
+
+func (b *Broadcaster) subscribe(queueSize int, policy OverflowPolicy, spectator bool) *Subscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	sub := &Subscription{
+		events:    make(chan *Event, queueSize),
+		policy:    policy,
+		spectator: spectator,
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe ends a subscription and closes its channel. it is fine to call more than once.
+func (b *Broadcaster) Unsubscribe(sub *Subscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.remove(sub)
+}
+
+func (b *Broadcaster) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscribed reports whether sub is still receiving events.
+func (b *Broadcaster) Subscribed(sub *Subscription) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	_, ok := b.subscribers[sub]
+	return ok
+}
+
+// SetSpectator changes whether sub gets the events that are just for spectators, for when someone watching starts playing.
+func (b *Broadcaster) SetSpectator(sub *Subscription, spectator bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	sub.spectator = spectator
+}
+
+// Close ends every subscription.
+func (b *Broadcaster) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+// Publish queues events for every subscriber, in order. it never blocks on a subscriber.
+func (b *Broadcaster) Publish(events ...*Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, event := range events {
+		b.published++
+		for sub := range b.subscribers {
+			b.deliver(sub, event)
+		}
+	}
+}
+
+func (b *Broadcaster) deliver(sub *Subscription, event *Event) {
+	if event.Type.spectatorOnly() && !sub.spectator {
+		return
+	}
+	if event.Type.droppable() && len(sub.events) >= cap(sub.events)/2 {
+		b.drop(sub)
+		return
+	}
+	select {
+	case sub.events <- event:
+		b.delivered++
+		return
+	default:
+	}
+
+	if event.Type.droppable() || sub.policy == OverflowDrop {
+		b.drop(sub)
+		return
+	}
+	b.disconnected++
+	b.remove(sub)
+}
+
+func (b *Broadcaster) drop(sub *Subscription) {
+	b.dropped++
+	sub.dropped.Add(1)
+}
+
+func (b *Broadcaster) Metrics() BroadcastMetrics {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return BroadcastMetrics{
+		Subscribers:  len(b.subscribers),
+		Published:    b.published,
+		Delivered:    b.delivered,
+		Dropped:      b.dropped,
+		Disconnected: b.disconnected,
+	}
+}
@@ -0,0 +1,29 @@
+package game
+
+import "errors"
+
+// errors callers may want to tell apart, with errors.Is. most come back from SubmitAnswer.
+// the messages are what players see, so they stay as they were before these had names.
+var (
+	ErrGameNotStarted     = errors.New("game is not started")
+	ErrGameEnded          = errors.New("game has already ended")
+	ErrGameAlreadyStarted = errors.New("game already started")
+	ErrGameNotEnded       = errors.New("game has not ended yet")
+	ErrLobbyNotWaiting    = errors.New("lobby is not in waiting state")
+	ErrPlayerNotFound     = errors.New("player not found")
+	ErrPlayerAlreadyAdded = errors.New("player with this sessionID is already added")
+	ErrPlayerNameTaken    = errors.New("player name is already taken in this lobby")
+	ErrInvalidPlayerName  = errors.New("invalid player name")
+	ErrInvalidChat        = errors.New("invalid chat message")
+	ErrWrongQuestion      = errors.New("incorrect question ID") // the answer is not for the question in front of the players
+	ErrAlreadyAnswered    = errors.New("player already answered this question")
+	ErrIncorrectAnswer    = errors.New("incorrect answer")
+	ErrAnswerCooldown     = errors.New("answered wrong too recently")
+	ErrInvalidAnswer      = errors.New("answer index out of range")
+	ErrNotEnoughQuestions = errors.New("not enough questions")
+	ErrPlayersNotReady    = errors.New("not enough players are ready")
+	ErrNotHost            = errors.New("only the host can do that")
+	ErrLobbyFull          = errors.New("lobby is full")
+	ErrWrongPassword      = errors.New("wrong lobby password")
+	ErrSpectator          = errors.New("spectators cannot do that")
+)
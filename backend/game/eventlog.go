@@ -0,0 +1,65 @@
+package game
+
+import "sort"
+
+// maxEventLogLength bounds how many past events a lobby keeps around for players who reconnect.
+// a full game is well under this, and anyone further behind than this still gets caught up by the snapshot.
+const maxEventLogLength = 500
+
+// SnapshotEvent is the state of the lobby right now, sent to a reconnecting player after any events they missed.
+// it is only ever sent to that one player, so it is not part of the lobby's event log.
+type SnapshotEvent struct {
+	State      GameState         `json:"state"`
+	Players    []PlayerSummary   `json:"players"`
+	Question   *QuestionEvent    `json:"question,omitempty"` // only while the game is started
+	Scoreboard []ScoreboardEntry `json:"scoreboard"`
+}
+
+// logEvent keeps an event for replay. must be called while holding the lobby mutex.
+func (g *GameLobby) logEvent(event *Event) {
+	g.eventLog = append(g.eventLog, event)
+	if len(g.eventLog) > maxEventLogLength {
+		g.eventLog = g.eventLog[len(g.eventLog)-maxEventLogLength:]
+	}
+}
+
+// Resume is for a player reconnecting after having seen events up to and including seq.
+// it returns the events they missed, oldest first, and a snapshot of the lobby as of the last of those events.
+func (g *GameLobby) Resume(sessionID string, seq uint64) ([]*Event, *Event, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	player, err := g.GetPlayer(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	missed, snapshot := g.resume(seq, player.Spectator)
+	return missed, snapshot, nil
+}
+
+// resume does the work for Resume and ResumeSpectating, leaving out the events that are just for spectators unless it is one.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) resume(seq uint64, spectator bool) ([]*Event, *Event) {
+	// the log is in seq order, so find the first event after seq and take everything from there.
+	first := sort.Search(len(g.eventLog), func(i int) bool {
+		return g.eventLog[i].Seq > seq
+	})
+	missed := make([]*Event, 0, len(g.eventLog)-first)
+	for _, event := range g.eventLog[first:] {
+		if spectator || !event.Type.spectatorOnly() {
+			missed = append(missed, event)
+		}
+	}
+
+	snapshot := SnapshotEvent{
+		State:      g.State,
+		Players:    g.roster(),
+		Scoreboard: g.scoreboard(),
+	}
+	if g.State == Started {
+		question := g.currentQuestionEvent()
+		snapshot.Question = &question
+	}
+	return missed, g.newEvent(EventSnapshot, snapshot)
+}
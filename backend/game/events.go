@@ -0,0 +1,150 @@
+package game
+
+import "time"
+
+// EventType tells clients what kind of payload an Event carries in its data.
+type EventType string
+
+const (
+	EventRoster           EventType = "roster"
+	EventSettings         EventType = "settings"
+	EventCountdown        EventType = "countdown"
+	EventQuestion         EventType = "question"
+	EventQuestionTimedOut EventType = "questionTimedOut"
+	EventReveal           EventType = "reveal"
+	EventAnswer           EventType = "answer"
+	EventScores           EventType = "scores"
+	EventChat             EventType = "chat"
+	EventGameOver         EventType = "gameOver"
+	EventRematch          EventType = "rematch"
+	EventSnapshot         EventType = "snapshot"
+)
+
+// EventTypes lists every event type a lobby can send, for anything that needs to enumerate them (like the schema check in the tests).
+var EventTypes = []EventType{
+	EventRoster,
+	EventSettings,
+	EventCountdown,
+	EventQuestion,
+	EventQuestionTimedOut,
+	EventReveal,
+	EventAnswer,
+	EventScores,
+	EventChat,
+	EventGameOver,
+	EventRematch,
+	EventSnapshot,
+}
+
+// Event is the envelope for every notification a lobby sends to its players.
+// Seq counts up by one for every event in a lobby, so clients can put them in order and notice gaps.
+type Event struct {
+	Type       EventType   `json:"type"`
+	Seq        uint64      `json:"seq"`
+	ServerTime int64       `json:"serverTime"` // unix milliseconds
+	Data       interface{} `json:"data"`
+}
+
+// droppable events are full snapshots or chatter, where a player who is not keeping up can miss some without losing track of the game.
+func (t EventType) droppable() bool {
+	return t == EventRoster || t == EventScores || t == EventChat
+}
+
+// spectatorOnly events only go to spectators, who are watching rather than playing. they still take a seq,
+// so players see a gap in the seqs where one went out.
+func (t EventType) spectatorOnly() bool {
+	return t == EventAnswer
+}
+
+type RosterEvent struct {
+	Players     []PlayerSummary `json:"players"`
+	Ready       int             `json:"ready"`       // how many players are ready
+	ReadyNeeded int             `json:"readyNeeded"` // how many need to be for the game to start, 0 when the lobby has no ready check
+	Spectators  int             `json:"spectators"`  // how many are watching without joining, see GameLobby.Spectate
+}
+
+// SettingsEvent is sent when the host changes the lobby's settings, see LobbySettings.
+type SettingsEvent struct {
+	QuestionCount     int            `json:"questionCount"`
+	CountdownMs       int            `json:"countdownMs"`
+	QuestionTimeoutMs int            `json:"questionTimeoutMs"`
+	QuestionFilter    QuestionFilter `json:"questionFilter"`
+	Scoring           string         `json:"scoring"`
+	RoundMode         RoundMode      `json:"roundMode"`
+	AnswerRules       AnswerRules    `json:"answerRules"`
+	ReadyCheck        ReadyCheck     `json:"readyCheck"`
+	JoinRules         JoinRules      `json:"joinRules"`
+	PasswordProtected bool           `json:"passwordProtected"`
+	QuestionBank      string         `json:"questionBank,omitempty"`
+}
+
+type CountdownEvent struct {
+	CountdownMs int `json:"countdownMs"`
+}
+
+type QuestionEvent struct {
+	Index         int            `json:"index"` // zero based position of this question in the game
+	QuestionCount int            `json:"questionCount"`
+	TimeoutMs     int            `json:"timeoutMs"` // 0 when there is no time limit
+	RoundMode     RoundMode      `json:"roundMode"`
+	Question      PublicQuestion `json:"question"`
+}
+
+type QuestionTimedOutEvent struct {
+	QuestionID string `json:"questionId"`
+	Solution
+}
+
+// RevealEvent closes a question in RoundEveryone.
+type RevealEvent struct {
+	QuestionID string `json:"questionId"`
+	Solution
+	Distribution []int           `json:"distribution"` // how many players picked each option, empty for numeric questions
+	Correct      []PlayerSummary `json:"correct"`      // who got it fully right, quickest first
+	Awards       []ScoreAward    `json:"awards"`
+}
+
+// AnswerEvent tells spectators what the answer was, once a question is over without a questionTimedOut or reveal event saying so.
+type AnswerEvent struct {
+	QuestionID string `json:"questionId"`
+	Solution
+	CorrectAnswer string `json:"correctAnswer"` // the answer written out, like the text of the correct option
+}
+
+type ScoresEvent struct {
+	Scoreboard []ScoreboardEntry `json:"scoreboard"`
+	Award      *ScoreAward       `json:"award,omitempty"` // the points that changed the scoreboard, if any
+}
+
+type ChatEvent struct {
+	From PlayerSummary `json:"from"`
+	Text string        `json:"text"`
+}
+
+type GameOverEvent struct {
+	WinningScore int               `json:"winningScore"`
+	Winners      []PlayerSummary   `json:"winners"`
+	Scoreboard   []ScoreboardEntry `json:"scoreboard"`
+	Round        int               `json:"round"`     // which of the lobby's games this was, see Rematch
+	Standings    []SeriesStanding  `json:"standings"` // running totals over every round so far, this one included
+}
+
+// newEvent wraps data in an event stamped with the lobby's latest sequence number.
+func (g *GameLobby) newEvent(eventType EventType, data interface{}) *Event {
+	return &Event{
+		Type:       eventType,
+		Seq:        g.eventSeq,
+		ServerTime: time.Now().UnixMilli(),
+		Data:       data,
+	}
+}
+
+// publish stamps an event with the next sequence number, logs it and queues it to go out to every player once the lobby is unlocked.
+// must be called while holding the lobby mutex, and the lobby must then be released with unlock rather than mutex.Unlock.
+func (g *GameLobby) publish(eventType EventType, data interface{}) *Event {
+	g.eventSeq++
+	event := g.newEvent(eventType, data)
+	g.logEvent(event)
+	g.outbox = append(g.outbox, event)
+	return event
+}
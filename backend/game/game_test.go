@@ -1,7 +1,12 @@
 package game
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -71,11 +76,11 @@ func answerQuestions(t *testing.T, gameLobby *GameLobby, playerID string, delays
 }
 
 func setupAndStartGame(t *testing.T, questionCount int, countdown int, questions []*Question) *GameLobby {
-	lobby := NewGameLobby(questionCount, countdown)
+	lobby := NewGameLobby(questionCount, countdown, 0)
 
 	// Add players
-	lobby.AddPlayer("player1")
-	lobby.AddPlayer("player2")
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
 
 	// Start the game with the provided questions
 	lobby.StartGame(questions)
@@ -162,7 +167,7 @@ func TestAddingLobbiesWithAndWithoutPlayer(t *testing.T) {
 	}
 
 	// Add the first game lobby without a player
-	lobbies.AddLobby(3, 100, nil)
+	lobbies.AddLobby(3, 100, 0, LobbyOptions{}, nil)
 
 	// Verify there is 1 game in the lobbies with no players
 	if len(lobbies.lobbies) != 1 {
@@ -177,7 +182,13 @@ func TestAddingLobbiesWithAndWithoutPlayer(t *testing.T) {
 
 	// Add a second game lobby with a player
 	player := &Player{SessionID: "player1"}
-	lobbies.AddLobby(5, 200, player)
+	lobbyID, added, err := lobbies.AddLobby(5, 200, 0, LobbyOptions{}, player)
+	if err != nil {
+		t.Fatalf("Failed to add a lobby with a player: %v", err)
+	}
+	if inLobby, _ := lobbies.lobbies[lobbyID].GetPlayer("player1"); added == nil || added != inLobby {
+		t.Errorf("Expected AddLobby to return the player it added to the lobby, got %v", added)
+	}
 
 	// Verify there are 2 lobbies
 	if len(lobbies.lobbies) != 2 {
@@ -194,3 +205,1338 @@ func TestAddingLobbiesWithAndWithoutPlayer(t *testing.T) {
 		t.Errorf("Expected 1 total player across all lobbies, found %d", playerCount)
 	}
 }
+
+// nextMessage waits for the next game message sent to a player, failing the test if none arrives in time.
+// roster and score updates are skipped over since they can show up in between any of the game messages.
+func nextMessage(t *testing.T, player *Player) *Event {
+	t.Helper()
+	for {
+		select {
+		case event := <-player.Messages():
+			if event.Type == EventRoster || event.Type == EventScores {
+				continue
+			}
+			return event
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for a message to player %s", player.SessionID)
+			return nil
+		}
+	}
+}
+
+func TestQuestionTimeoutAdvancesQuestion(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	// no time limit configured, we drive the expiry by hand so nothing here depends on a real timer.
+	lobby := setupAndStartGame(t, 2, 0, questions)
+	player := lobby.Players[0]
+	if event := nextMessage(t, player); event.Type != EventCountdown {
+		t.Fatalf("expected a countdown event first, got %+v", event)
+	}
+	if event := nextMessage(t, player); event.Type != EventQuestion {
+		t.Fatalf("expected the first question next, got %+v", event)
+	}
+
+	firstQuestion := lobby.Questions[0]
+	lobby.questionTimedOut(0)
+	if lobby.CurrentQuestionIndex != 1 {
+		t.Fatalf("expected question index 1 after timeout, got %d", lobby.CurrentQuestionIndex)
+	}
+	event := nextMessage(t, player)
+	timedOut, ok := event.Data.(QuestionTimedOutEvent)
+	if event.Type != EventQuestionTimedOut || !ok {
+		t.Fatalf("expected a questionTimedOut event, got %+v", event)
+	}
+	if timedOut.QuestionID != firstQuestion.ID || timedOut.CorrectIndex != firstQuestion.CorrectIndex {
+		t.Errorf("timeout message should reveal the answer to %s, got %+v", firstQuestion.ID, timedOut)
+	}
+	if event := nextMessage(t, player); event.Type != EventQuestion || event.Data.(QuestionEvent).Index != 1 {
+		t.Fatalf("expected the second question next, got %+v", event)
+	}
+
+	// a stale expiry for a question that already moved on must be ignored.
+	lobby.questionTimedOut(0)
+	if lobby.CurrentQuestionIndex != 1 || lobby.State != Started {
+		t.Fatalf("stale timeout should not change the game, index %d state %v", lobby.CurrentQuestionIndex, lobby.State)
+	}
+
+	lobby.questionTimedOut(1)
+	if lobby.State != Ended {
+		t.Fatalf("expected game to end after the last question timed out, got %v", lobby.State)
+	}
+	for _, player := range lobby.Players {
+		if player.Score != 0 {
+			t.Errorf("nobody answered so nobody should score, player %s has %d", player.SessionID, player.Score)
+		}
+	}
+}
+
+func TestQuestionTimerEndsGameWithAfkPlayers(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+		{ID: "q3", QuestionText: "Question 3", Options: []string{"A", "B", "C"}, CorrectIndex: 0},
+	}
+	lobby := NewGameLobby(3, 0, 10)
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.StartGame(questions)
+
+	// player1 answers the first question wrong and then walks away, player2 never answers at all.
+	player := lobby.Players[0]
+	nextMessage(t, player) // countdown
+	nextMessage(t, player) // first question
+	lobby.mutex.Lock()
+	firstQuestion := lobby.Questions[0]
+	lobby.mutex.Unlock()
+	if err, _ := lobby.SubmitAnswer("player1", firstQuestion.ID, (firstQuestion.CorrectIndex+1)%3); err == nil {
+		t.Fatalf("expected the wrong answer to be rejected")
+	}
+
+	timeouts := 0
+	var lastSeq uint64
+	for gameOver := false; !gameOver; {
+		event := nextMessage(t, player)
+		if event.Seq <= lastSeq {
+			t.Errorf("expected event sequence numbers to increase, got %d after %d", event.Seq, lastSeq)
+		}
+		lastSeq = event.Seq
+		switch event.Type {
+		case EventQuestionTimedOut:
+			timeouts++
+		case EventGameOver:
+			gameOver = true
+		}
+	}
+	if timeouts != 3 {
+		t.Errorf("expected all 3 questions to time out, got %d", timeouts)
+	}
+	lobby.mutex.Lock()
+	defer lobby.mutex.Unlock()
+	if lobby.State != Ended {
+		t.Errorf("expected game state to be Ended, got %v", lobby.State)
+	}
+}
+
+func TestPlayerNames(t *testing.T) {
+	lobby := NewGameLobby(3, 0, 0)
+
+	alice, err := lobby.AddPlayer("session1", "  Alice   Smith ")
+	if err != nil {
+		t.Fatalf("failed to add player: %v", err)
+	}
+	if alice.Name != "Alice Smith" {
+		t.Errorf("expected whitespace to be tidied up in the name, got %q", alice.Name)
+	}
+	if alice.ID == "" || alice.ID == alice.SessionID {
+		t.Errorf("expected a public player id separate from the session id, got %q", alice.ID)
+	}
+
+	if _, err := lobby.AddPlayer("session2", "alice smith"); err != ErrPlayerNameTaken {
+		t.Errorf("expected the name to be taken regardless of case, got %v", err)
+	}
+	if _, err := lobby.AddPlayer("session2", "A name that is far too long for anyone"); err == nil {
+		t.Errorf("expected a too long name to be rejected")
+	}
+	if _, err := lobby.AddPlayer("session2", "bell\a"); err == nil {
+		t.Errorf("expected a name with control characters to be rejected")
+	}
+
+	// a blank name gets a generated one that doesn't clash with anyone who picked such a name themselves.
+	if _, err := lobby.AddPlayer("session2", "Player 3"); err != nil {
+		t.Fatalf("failed to add player: %v", err)
+	}
+	anonymous, err := lobby.AddPlayer("session3", "")
+	if err != nil {
+		t.Fatalf("failed to add player: %v", err)
+	}
+	if anonymous.Name != "Player 4" {
+		t.Errorf("expected a generated name of Player 4, got %q", anonymous.Name)
+	}
+}
+
+func TestRosterUpdates(t *testing.T) {
+	lobby := NewGameLobby(3, 0, 0)
+	alice, _ := lobby.AddPlayer("session1", "Alice")
+	bob, _ := lobby.AddPlayer("session2", "Bob")
+
+	// the latest roster alice has been sent should include both players.
+	var roster []PlayerSummary
+	for len(alice.Messages()) > 0 {
+		roster = (<-alice.Messages()).Data.(RosterEvent).Players
+	}
+	if len(roster) != 2 || roster[0] != alice.Summary() || roster[1] != bob.Summary() {
+		t.Fatalf("unexpected roster after joins: %+v", roster)
+	}
+
+	if err := lobby.RemovePlayer("session1"); err != nil {
+		t.Fatalf("failed to remove player: %v", err)
+	}
+	if _, ok := <-alice.Messages(); ok {
+		t.Errorf("expected the message channel of a player who left to be closed")
+	}
+	for len(bob.Messages()) > 0 {
+		roster = (<-bob.Messages()).Data.(RosterEvent).Players
+	}
+	if len(roster) != 1 || roster[0] != bob.Summary() {
+		t.Fatalf("unexpected roster after leaving: %+v", roster)
+	}
+	if err := lobby.RemovePlayer("session1"); err == nil {
+		t.Errorf("expected an error removing a player who already left")
+	}
+}
+
+func TestScoreboard(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+		{ID: "q3", QuestionText: "Question 3", Options: []string{"A", "B", "C"}, CorrectIndex: 0},
+	}
+	lobby := setupAndStartGame(t, 3, 0, questions)
+	lobby.AddPlayer("player3", "") // too late, the game already started.
+
+	answer := func(sessionID string, correct bool) {
+		lobby.mutex.Lock()
+		question := lobby.Questions[lobby.CurrentQuestionIndex]
+		lobby.mutex.Unlock()
+		answerIndex := question.CorrectIndex
+		if !correct {
+			answerIndex = (answerIndex + 1) % len(question.Options)
+		}
+		lobby.SubmitAnswer(sessionID, question.ID, answerIndex)
+	}
+	answer("player2", false)
+	answer("player1", true)
+	answer("player2", true)
+
+	// the latest scores event should agree with the scoreboard.
+	var scores []ScoreboardEntry
+	for len(lobby.Players[0].Messages()) > 0 {
+		if event := <-lobby.Players[0].Messages(); event.Type == EventScores {
+			scores = event.Data.(ScoresEvent).Scoreboard
+		}
+	}
+	scoreboard := lobby.Scoreboard()
+	if len(scores) != 2 || scores[0] != scoreboard[0] || scores[1] != scoreboard[1] {
+		t.Fatalf("expected the last scores event to match the scoreboard, got %+v and %+v", scores, scoreboard)
+	}
+
+	// both players are on 10 points so they share first place, player1 is listed first having never answered wrong.
+	player1, player2 := scoreboard[0], scoreboard[1]
+	if player1.ID != lobby.Players[0].ID || player1.Rank != 1 || player1.Score != 10 || player1.CorrectCount != 1 || player1.WrongCount != 0 {
+		t.Errorf("unexpected scoreboard entry for player1: %+v", player1)
+	}
+	if player2.ID != lobby.Players[1].ID || player2.Rank != 1 || player2.Score != 10 || player2.CorrectCount != 1 || player2.WrongCount != 1 {
+		t.Errorf("unexpected scoreboard entry for player2: %+v", player2)
+	}
+
+	answer("player1", true)
+	scoreboard = lobby.GameStatus().Scoreboard
+	if scoreboard[0].Rank != 1 || scoreboard[0].Score != 20 || scoreboard[1].Rank != 2 {
+		t.Errorf("expected player1 to be alone in first place, got %+v", scoreboard)
+	}
+}
+
+func TestResumeReplaysMissedEvents(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	lobby := setupAndStartGame(t, 2, 0, questions)
+	player := lobby.Players[0]
+	nextMessage(t, player) // countdown
+	firstQuestion := nextMessage(t, player)
+
+	// the player's tab goes away here and misses the timeout and the next question.
+	lobby.questionTimedOut(0)
+
+	missed, snapshot, err := lobby.Resume(player.SessionID, firstQuestion.Seq)
+	if err != nil {
+		t.Fatalf("failed to resume: %v", err)
+	}
+	if len(missed) != 2 || missed[0].Type != EventQuestionTimedOut || missed[1].Type != EventQuestion {
+		t.Fatalf("expected the timeout and the second question to be replayed, got %+v", missed)
+	}
+	if missed[0].Seq != firstQuestion.Seq+1 || missed[1].Seq != firstQuestion.Seq+2 {
+		t.Errorf("expected replayed events to follow on from seq %d, got %d and %d", firstQuestion.Seq, missed[0].Seq, missed[1].Seq)
+	}
+
+	state := snapshot.Data.(SnapshotEvent)
+	if snapshot.Type != EventSnapshot || snapshot.Seq != missed[1].Seq {
+		t.Errorf("expected a snapshot as of the last replayed event, got %+v", snapshot)
+	}
+	if state.State != Started || state.Question == nil || state.Question.Index != 1 || state.Question.Question.ID != lobby.Questions[1].ID {
+		t.Errorf("expected the snapshot to have the second question, got %+v", state)
+	}
+	if len(state.Players) != 2 || len(state.Scoreboard) != 2 {
+		t.Errorf("expected the snapshot to have both players, got %+v", state)
+	}
+
+	// caught up already, nothing to replay.
+	if missed, _, _ := lobby.Resume(player.SessionID, snapshot.Seq); len(missed) != 0 {
+		t.Errorf("expected nothing to replay, got %+v", missed)
+	}
+	if _, _, err := lobby.Resume("nobody", 0); err == nil {
+		t.Errorf("expected an unknown player to be refused")
+	}
+}
+
+func TestBroadcasterWithHundredsOfSubscribers(t *testing.T) {
+	const readers, stalled, batches, batchSize = 300, 200, 20, 30
+	broadcaster := NewBroadcaster()
+
+	// readers keep up and count what they get, in order. stalled subscribers never read anything.
+	var received sync.WaitGroup
+	var outOfOrder, delivered atomic.Int64
+	for i := 0; i < readers; i++ {
+		sub := broadcaster.Subscribe(subscriberQueueSize, OverflowDisconnect)
+		received.Add(1)
+		go func() {
+			defer received.Done()
+			var lastSeq uint64
+			for event := range sub.Events() {
+				if event.Seq <= lastSeq {
+					outOfOrder.Add(1)
+				}
+				lastSeq = event.Seq
+				delivered.Add(1)
+			}
+		}()
+	}
+	stalledSubs := make([]*Subscription, stalled)
+	for i := range stalledSubs {
+		stalledSubs[i] = broadcaster.Subscribe(subscriberQueueSize, OverflowDisconnect)
+	}
+
+	var seq uint64
+	for batch := 0; batch < batches; batch++ {
+		events := make([]*Event, batchSize)
+		for i := range events {
+			seq++
+			eventType := EventQuestion
+			if i%3 == 0 {
+				eventType = EventScores
+			}
+			events[i] = &Event{Type: eventType, Seq: seq}
+		}
+
+		// publishing must never wait on anyone, however far behind they are.
+		published := make(chan struct{})
+		go func() {
+			broadcaster.Publish(events...)
+			close(published)
+		}()
+		select {
+		case <-published:
+		case <-time.After(time.Second):
+			t.Fatalf("publish blocked on batch %d", batch)
+		}
+
+		// let the readers catch up before the next batch so that only the stalled subscribers overflow.
+		for delivered.Load() < int64(readers*(batch+1)*batchSize) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	metrics := broadcaster.Metrics()
+	if metrics.Subscribers != readers {
+		t.Errorf("expected only the %d readers to still be subscribed, got %d", readers, metrics.Subscribers)
+	}
+	if metrics.Disconnected != stalled {
+		t.Errorf("expected all %d stalled subscribers to be disconnected, got %d", stalled, metrics.Disconnected)
+	}
+	if metrics.Published != batches*batchSize {
+		t.Errorf("expected %d events published, got %d", batches*batchSize, metrics.Published)
+	}
+	for _, sub := range stalledSubs {
+		// droppable events stop being queued at half the queue, the rest fills up with essential events until it overflows.
+		if sub.Dropped() == 0 || broadcaster.Subscribed(sub) {
+			t.Fatalf("expected stalled subscribers to drop events and then be disconnected")
+		}
+	}
+
+	broadcaster.Close()
+	received.Wait()
+	if outOfOrder.Load() != 0 {
+		t.Errorf("%d events were delivered out of order", outOfOrder.Load())
+	}
+	if delivered.Load() != readers*batches*batchSize {
+		t.Errorf("expected readers to get every event, got %d of %d", delivered.Load(), readers*batches*batchSize)
+	}
+}
+
+func TestPlayersWhoNeverConnectCannotWedgeLobby(t *testing.T) {
+	const players, questionCount = 200, 100
+	questions := make([]*Question, questionCount)
+	for i := range questions {
+		questions[i] = &Question{ID: fmt.Sprintf("q%d", i), QuestionText: "Question", Options: []string{"A", "B"}, CorrectIndex: 1}
+	}
+	lobby := NewGameLobby(questionCount, 0, 0)
+	for i := 0; i < players; i++ {
+		if _, err := lobby.AddPlayer(fmt.Sprintf("player%d", i), ""); err != nil {
+			t.Fatalf("failed to add player: %v", err)
+		}
+	}
+	lobby.StartGame(questions)
+
+	// nobody ever reads their events, but one player answers every question as fast as they can.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			lobby.mutex.Lock()
+			state, index := lobby.State, lobby.CurrentQuestionIndex
+			lobby.mutex.Unlock()
+			if state == Ended {
+				return
+			}
+			if state != Started {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			lobby.SubmitAnswer("player0", lobby.Questions[index].ID, 1)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("game got stuck delivering to players who are not listening")
+	}
+
+	metrics := lobby.BroadcastMetrics()
+	if metrics.Disconnected != players || metrics.Subscribers != 0 {
+		t.Errorf("expected every player to fall behind and be disconnected, got %+v", metrics)
+	}
+	if lobby.Players[0].Score != questionCount*10 {
+		t.Errorf("expected player0 to have answered everything, got %d", lobby.Players[0].Score)
+	}
+
+	// coming back gets a fresh subscription, with the event log to catch up from.
+	sub, err := lobby.Connect("player1")
+	if err != nil || !lobby.broadcaster.Subscribed(sub) {
+		t.Fatalf("expected a fresh subscription on reconnect, got %v", err)
+	}
+	_, snapshot, _ := lobby.Resume("player1", 0)
+	if snapshot.Data.(SnapshotEvent).State != Ended {
+		t.Errorf("expected the snapshot to show the game is over")
+	}
+}
+
+func TestSecondConnectionTakesOver(t *testing.T) {
+	lobby := NewGameLobby(1, 0, 0)
+	lobby.AddPlayer("player1", "")
+	first, _ := lobby.Connect("player1")
+	second, err := lobby.Connect("player1")
+	if err != nil || first == second || !lobby.broadcaster.Subscribed(second) || lobby.broadcaster.Subscribed(first) {
+		t.Fatalf("expected the second websocket to get its own subscription and end the first, got %v", err)
+	}
+	for range first.Events() {
+		// whatever was queued before the second websocket took over, then the channel closes.
+	}
+
+	lobby.AddPlayer("player2", "")
+	if event := <-second.Events(); event.Type != EventRoster {
+		t.Errorf("expected the second websocket to get the roster, got %+v", event)
+	}
+	// the first websocket going away leaves the player connected through the second.
+	lobby.Disconnect("player1")
+	if !lobby.Players[0].host {
+		t.Error("expected player1 to stay host while their second websocket is open")
+	}
+}
+
+func TestQuestionFilterSelection(t *testing.T) {
+	pool := []*Question{
+		{ID: "e1", Category: "Equity", Difficulty: 1},
+		{ID: "e2", Category: "equity", Difficulty: 2, Tags: []string{"dilution"}},
+		{ID: "e3", Category: "Equity", Difficulty: 3},
+		{ID: "f1", Category: "Fundraising", Difficulty: 1, Tags: []string{"dilution"}},
+		{ID: "f2", Category: "Fundraising", Difficulty: 3},
+		{ID: "u1", Category: "Equity"},
+	}
+	ids := func(questions []*Question) map[string]bool {
+		found := make(map[string]bool)
+		for _, q := range questions {
+			found[q.ID] = true
+		}
+		return found
+	}
+
+	selected, err := QuestionFilter{Categories: []string{"equity"}, MinDifficulty: 2}.Select(pool, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := ids(selected); len(found) != 2 || !found["e2"] || !found["e3"] {
+		t.Errorf("expected e2 and e3, got %v", found)
+	}
+
+	selected, err = QuestionFilter{Tags: []string{"DILUTION"}}.Select(pool, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found := ids(selected); len(found) != 2 || !found["e2"] || !found["f1"] {
+		t.Errorf("expected e2 and f1, got %v", found)
+	}
+
+	selected, err = QuestionFilter{DifficultyMix: map[int]int{1: 2, 3: 1}}.Select(pool, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	difficulties := make(map[int]int)
+	for _, q := range selected {
+		difficulties[q.Difficulty]++
+	}
+	if difficulties[1] != 2 || difficulties[3] != 1 || len(selected) != 3 {
+		t.Errorf("expected two easy questions and one hard one, got %v", difficulties)
+	}
+
+	// the filter can't fill the count, or the mix asks for more than there is.
+	if _, err := (QuestionFilter{Categories: []string{"fundraising"}}).Select(pool, 3); !errors.Is(err, ErrNotEnoughQuestions) {
+		t.Errorf("expected ErrNotEnoughQuestions, got %v", err)
+	}
+	if _, err := (QuestionFilter{DifficultyMix: map[int]int{2: 2}}).Select(pool, 2); !errors.Is(err, ErrNotEnoughQuestions) {
+		t.Errorf("expected ErrNotEnoughQuestions, got %v", err)
+	}
+	// nonsense filters are refused outright.
+	if _, err := (QuestionFilter{DifficultyMix: map[int]int{1: 1}}).Select(pool, 2); err == nil || errors.Is(err, ErrNotEnoughQuestions) {
+		t.Errorf("expected a mix that doesn't add up to the count to be invalid, got %v", err)
+	}
+	if _, err := (QuestionFilter{MinDifficulty: 4, MaxDifficulty: 2}).Select(pool, 0); err == nil {
+		t.Error("expected a backwards difficulty range to be invalid")
+	}
+	if _, err := (QuestionFilter{}).Select(pool, -1); err == nil {
+		t.Error("expected a negative question count to be invalid")
+	}
+
+	// a lobby whose filter can't be satisfied does not start.
+	lobby := NewGameLobby(3, 0, 0)
+	lobby.QuestionFilter = QuestionFilter{Categories: []string{"fundraising"}}
+	lobby.AddPlayer("player1", "")
+	if err := lobby.StartGame(pool); !errors.Is(err, ErrNotEnoughQuestions) {
+		t.Errorf("expected the game not to start, got %v", err)
+	}
+	if lobby.State != Waiting {
+		t.Errorf("expected the lobby to still be waiting, got %v", lobby.State)
+	}
+}
+
+func TestScoringStrategies(t *testing.T) {
+	hard := &Question{ID: "q1", Difficulty: 4}
+	window := 20 * time.Second
+	cases := []struct {
+		strategy ScoringStrategy
+		ctx      ScoringContext
+		points   int
+	}{
+		{FlatScoring{}, ScoringContext{Question: hard, Latency: time.Second, Window: window}, 10},
+		{DifficultyScoring{}, ScoringContext{Question: hard, Latency: time.Second, Window: window}, 40},
+		{DifficultyScoring{}, ScoringContext{Question: &Question{ID: "q2"}, Window: window}, 10},
+		{SpeedScoring{}, ScoringContext{Question: hard, Latency: 0, Window: window}, 100},
+		{SpeedScoring{}, ScoringContext{Question: hard, Latency: 10 * time.Second, Window: window}, 55},
+		{SpeedScoring{}, ScoringContext{Question: hard, Latency: time.Minute, Window: window}, 10},
+		{EveryoneScoring{}, ScoringContext{Question: hard, Latency: 10 * time.Second, Window: window, Position: 2}, 750},
+	}
+	for _, c := range cases {
+		award := c.strategy.Score(c.ctx)
+		if award.Points != c.points || award.Explanation == "" {
+			t.Errorf("%s scoring with %+v: expected %d points with an explanation, got %+v", c.strategy.Name(), c.ctx, c.points, award)
+		}
+	}
+
+	for _, name := range ScoringStrategyNames() {
+		if strategy, err := ScoringStrategyByName(name); err != nil || strategy.Name() != name {
+			t.Errorf("expected to find the %s strategy, got %v, %v", name, strategy, err)
+		}
+	}
+	if _, err := ScoringStrategyByName("golf"); err == nil {
+		t.Error("expected an unknown strategy to be refused")
+	}
+}
+
+func TestEveryoneScoringKeepsQuestionOpen(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	lobby := NewGameLobby(2, 0, 0)
+	lobby.Scoring = EveryoneScoring{}
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.AddPlayer("player3", "")
+	lobby.StartGame(questions)
+	time.Sleep(time.Millisecond)
+
+	first := lobby.Questions[0]
+	err, result := lobby.SubmitAnswer("player1", first.ID, first.CorrectIndex)
+	if err != nil || !result.Correct || result.Points < 500 || result.Score != result.Points {
+		t.Fatalf("expected player1 to score, got %v %+v", err, result)
+	}
+	if lobby.CurrentQuestionIndex != 0 {
+		t.Fatal("expected the question to stay open for the other players")
+	}
+	if err, result := lobby.SubmitAnswer("player2", first.ID, first.CorrectIndex); err != nil || result.Points < 500 {
+		t.Fatalf("expected player2 to score too, got %v %+v", err, result)
+	}
+	// the last player getting it wrong still closes the question, since everyone has had their go.
+	lobby.SubmitAnswer("player3", first.ID, (first.CorrectIndex+1)%3)
+	if lobby.CurrentQuestionIndex != 1 {
+		t.Fatal("expected the game to move on once everyone had answered")
+	}
+
+	var award2 *ScoreAward
+	for len(lobby.Players[2].Messages()) > 0 {
+		if event := <-lobby.Players[2].Messages(); event.Type == EventScores && event.Data.(ScoresEvent).Award != nil {
+			award2 = event.Data.(ScoresEvent).Award
+		}
+	}
+	if award2 == nil || award2.PlayerID != lobby.Players[1].ID || award2.Explanation == "" {
+		t.Errorf("expected the scores events to explain player2's points, got %+v", award2)
+	}
+}
+
+func TestEveryoneAnswersRoundMode(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	lobby := NewGameLobby(2, 0, 50)
+	lobby.RoundMode = RoundEveryone
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.AddPlayer("player3", "")
+	lobby.StartGame(questions)
+	time.Sleep(time.Millisecond)
+
+	first := lobby.Questions[0]
+	if err, _ := lobby.SubmitAnswer("player1", first.ID, 7); err == nil {
+		t.Fatal("expected an answer that isn't one of the options to be refused")
+	}
+	if err, result := lobby.SubmitAnswer("player1", first.ID, first.CorrectIndex); err != nil || !result.Accepted || result.Correct || result.Points != 0 {
+		t.Fatalf("expected the answer to be locked in without any points yet, got %v %+v", err, result)
+	}
+	lobby.SubmitAnswer("player2", first.ID, (first.CorrectIndex+1)%3)
+	if lobby.CurrentQuestionIndex != 0 || lobby.Players[0].Score != 0 {
+		t.Fatal("expected the question to stay open, unscored, until everyone has answered")
+	}
+	lobby.SubmitAnswer("player3", first.ID, first.CorrectIndex)
+	if lobby.CurrentQuestionIndex != 1 {
+		t.Fatal("expected the question to close once everyone had answered")
+	}
+
+	var reveal *RevealEvent
+	for len(lobby.Players[1].Messages()) > 0 {
+		if event := <-lobby.Players[1].Messages(); event.Type == EventReveal {
+			r := event.Data.(RevealEvent)
+			reveal = &r
+		}
+	}
+	if reveal == nil {
+		t.Fatal("expected a reveal event")
+	}
+	if reveal.CorrectIndex != first.CorrectIndex || reveal.Distribution[first.CorrectIndex] != 2 || reveal.Distribution[(first.CorrectIndex+1)%3] != 1 {
+		t.Errorf("unexpected reveal %+v", reveal)
+	}
+	if len(reveal.Correct) != 2 || reveal.Correct[0].ID != lobby.Players[0].ID || reveal.Correct[1].ID != lobby.Players[2].ID {
+		t.Errorf("expected player1 then player3 to be shown as correct, got %+v", reveal.Correct)
+	}
+	// with flat scoring only the quickest correct answer scores.
+	if len(reveal.Awards) != 1 || reveal.Awards[0].PlayerID != lobby.Players[0].ID || lobby.Players[0].Score != 10 || lobby.Players[2].Score != 0 {
+		t.Errorf("expected only player1 to score, got %+v", reveal.Awards)
+	}
+
+	// the timer closes the question when someone doesn't answer.
+	second := lobby.Questions[1]
+	lobby.SubmitAnswer("player2", second.ID, second.CorrectIndex)
+	time.Sleep(100 * time.Millisecond)
+	status := lobby.GameStatus()
+	if status.State != Ended || lobby.Players[1].Score != 10 {
+		t.Errorf("expected the timer to reveal the last question and end the game with player2 scoring, got %+v", status)
+	}
+}
+
+func TestWrongAnswerPenaltyAndRetry(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	lobby := NewGameLobby(2, 0, 0)
+	lobby.AnswerRules = AnswerRules{WrongAnswerPenalty: 3, AnswerPolicy: RetryAfterCooldown, RetryCooldownMs: 20, LockoutAfterWrong: 2}
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.StartGame(questions)
+	time.Sleep(time.Millisecond)
+
+	first := lobby.Questions[0]
+	wrong := (first.CorrectIndex + 1) % 3
+	err, result := lobby.SubmitAnswer("player1", first.ID, wrong)
+	if err == nil || !result.Accepted || result.Points != -3 || result.Score != -3 || result.LockedOut || result.RetryAfterMs != 20 {
+		t.Fatalf("expected a penalty and a cooldown, got %v %+v", err, result)
+	}
+	// too soon to try again.
+	err, result = lobby.SubmitAnswer("player1", first.ID, first.CorrectIndex)
+	if err == nil || result.Accepted || result.RetryAfterMs <= 0 || result.Score != -3 {
+		t.Fatalf("expected the retry to be refused during the cooldown, got %v %+v", err, result)
+	}
+	time.Sleep(25 * time.Millisecond)
+	err, result = lobby.SubmitAnswer("player1", first.ID, wrong)
+	if err == nil || !result.LockedOut || result.WrongAnswers != 2 || result.Score != -6 {
+		t.Fatalf("expected the second wrong answer to lock player1 out, got %v %+v", err, result)
+	}
+	if err, _ := lobby.SubmitAnswer("player1", first.ID, first.CorrectIndex); err == nil {
+		t.Fatal("expected a locked out player to be refused")
+	}
+
+	// player2 can still get it, but player1 is locked out of the rest of the game.
+	if err, result := lobby.SubmitAnswer("player2", first.ID, first.CorrectIndex); err != nil || !result.Correct || result.Points != 10 {
+		t.Fatalf("expected player2 to score, got %v %+v", err, result)
+	}
+	second := lobby.Questions[1]
+	if err, result := lobby.SubmitAnswer("player1", second.ID, second.CorrectIndex); !errors.Is(err, ErrAlreadyAnswered) || !result.LockedOut || result.WrongAnswers != 2 {
+		t.Fatalf("expected player1 to stay locked out on the next question, got %v %+v", err, result)
+	}
+	// player1 is not waited on, so player2 answering ends the game.
+	lobby.SubmitAnswer("player2", second.ID, second.CorrectIndex)
+	if lobby.State != Ended {
+		t.Fatal("expected the game to end without waiting on the locked out player")
+	}
+
+	if err := (AnswerRules{AnswerPolicy: RetryAfterCooldown}).Validate(RoundEveryone); err == nil {
+		t.Error("expected retrying to be refused in the everyone answers round mode")
+	}
+	if err := (AnswerRules{LockoutAfterWrong: 2}).Validate(RoundRace); err == nil {
+		t.Error("expected a lockout without the retry policy to be refused")
+	}
+}
+
+func TestLockoutCountsWrongAnswersAcrossQuestions(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+		{ID: "q3", QuestionText: "Question 3", Options: []string{"A", "B", "C"}, CorrectIndex: 0},
+	}
+	lobby := NewGameLobby(3, 0, 0)
+	lobby.AnswerRules = AnswerRules{AnswerPolicy: RetryAfterCooldown, LockoutAfterWrong: 2}
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.StartGame(questions)
+	time.Sleep(time.Millisecond)
+
+	first := lobby.Questions[0]
+	if err, result := lobby.SubmitAnswer("player1", first.ID, (first.CorrectIndex+1)%3); result.LockedOut || result.WrongAnswers != 1 {
+		t.Fatalf("expected one wrong answer not to lock player1 out, got %v %+v", err, result)
+	}
+	if err, result := lobby.SubmitAnswer("player1", first.ID, first.CorrectIndex); err != nil || !result.Correct || result.WrongAnswers != 1 {
+		t.Fatalf("expected player1 to get it on the retry, got %v %+v", err, result)
+	}
+	second := lobby.Questions[1]
+	if err, result := lobby.SubmitAnswer("player1", second.ID, (second.CorrectIndex+1)%3); !result.LockedOut || result.WrongAnswers != 2 {
+		t.Fatalf("expected a wrong answer on another question to count towards the lockout, got %v %+v", err, result)
+	}
+	lobby.SubmitAnswer("player2", second.ID, second.CorrectIndex)
+	third := lobby.Questions[2]
+	if err, result := lobby.SubmitAnswer("player1", third.ID, third.CorrectIndex); !errors.Is(err, ErrAlreadyAnswered) || !result.LockedOut {
+		t.Fatalf("expected player1 to be locked out of the rest of the game, got %v %+v", err, result)
+	}
+
+	// once nobody can answer, the game is over even with questions left.
+	lobby = NewGameLobby(3, 0, 0)
+	lobby.AnswerRules = AnswerRules{AnswerPolicy: RetryAfterCooldown, LockoutAfterWrong: 1}
+	lobby.AddPlayer("player1", "")
+	lobby.StartGame(questions)
+	time.Sleep(time.Millisecond)
+	first = lobby.Questions[0]
+	lobby.SubmitAnswer("player1", first.ID, (first.CorrectIndex+1)%3)
+	if lobby.State != Ended {
+		t.Fatal("expected the game to end once every player was locked out")
+	}
+}
+
+func TestWinnersWithEveryScoreNegative(t *testing.T) {
+	questions := []*Question{{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1}}
+	lobby := NewGameLobby(1, 0, 0)
+	lobby.AnswerRules = AnswerRules{WrongAnswerPenalty: 3}
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.StartGame(questions)
+	time.Sleep(time.Millisecond)
+
+	lobby.SubmitAnswer("player1", "q1", 0)
+	lobby.SubmitAnswer("player2", "q1", 2)
+	status := lobby.GameStatus()
+	if status.State != Ended {
+		t.Fatal("expected the game to end once everyone was locked out")
+	}
+	if status.WinningScore != -3 || len(status.Winners) != 2 {
+		t.Errorf("expected both players to win on -3, got %d %+v", status.WinningScore, status.Winners)
+	}
+
+	lobby.Players[1].Score = -5
+	if status := lobby.GameStatus(); status.WinningScore != -3 || len(status.Winners) != 1 || status.Winners[0].ID != lobby.Players[0].ID {
+		t.Errorf("expected the least negative score to win, got %d %+v", status.WinningScore, status.Winners)
+	}
+}
+
+func TestSubmitAnswerErrors(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+	}
+	lobby := NewGameLobby(1, 0, 0)
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	if err, _ := lobby.SubmitAnswer("player1", "q1", 1); !errors.Is(err, ErrGameNotStarted) {
+		t.Errorf("expected ErrGameNotStarted, got %v", err)
+	}
+	lobby.StartGame(questions)
+	time.Sleep(time.Millisecond)
+	if err := lobby.StartGame(questions); !errors.Is(err, ErrGameAlreadyStarted) {
+		t.Errorf("expected ErrGameAlreadyStarted, got %v", err)
+	}
+	if _, err := lobby.AddPlayer("player3", ""); !errors.Is(err, ErrLobbyNotWaiting) {
+		t.Errorf("expected ErrLobbyNotWaiting, got %v", err)
+	}
+
+	cases := []struct {
+		sessionID, questionID string
+		answer                int
+		expected              error
+	}{
+		{"nobody", "q1", 1, ErrPlayerNotFound},
+		{"player1", "q2", 1, ErrWrongQuestion},
+		{"player1", "q1", 0, ErrIncorrectAnswer},
+		{"player1", "q1", 1, ErrAlreadyAnswered},
+		{"player2", "q1", 1, nil},
+		{"player2", "q1", 1, ErrGameEnded},
+	}
+	for _, c := range cases {
+		if err, _ := lobby.SubmitAnswer(c.sessionID, c.questionID, c.answer); !errors.Is(err, c.expected) || (c.expected == nil && err != nil) {
+			t.Errorf("%s answering %s with %d: expected %v, got %v", c.sessionID, c.questionID, c.answer, c.expected, err)
+		}
+	}
+}
+
+func TestReadyCheck(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+	}
+	lobby := NewGameLobby(1, 0, 0)
+	lobby.ReadyCheck = ReadyCheck{ReadyPolicy: ReadyAll}
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.SetPlayerReady("player1", true)
+	if err := lobby.StartGame(questions); !errors.Is(err, ErrPlayersNotReady) {
+		t.Fatalf("expected the game not to start with only one of two players ready, got %v", err)
+	}
+	lobby.SetPlayerReady("player2", true)
+	if err := lobby.StartGame(questions); err != nil {
+		t.Fatalf("expected the game to start with everyone ready, got %v", err)
+	}
+
+	// a quorum that starts by itself, including when an unready player leaves.
+	lobby = NewGameLobby(1, 0, 0)
+	lobby.ReadyCheck = ReadyCheck{ReadyPolicy: ReadyQuorum, ReadyQuorum: 2, AutoStart: true}
+	lobby.QuestionPool = func() []*Question { return questions }
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.AddPlayer("player3", "")
+	lobby.SetPlayerReady("player1", true)
+	if lobby.GameStatus().State != Waiting {
+		t.Fatal("expected the lobby to wait for a second ready player")
+	}
+	var roster RosterEvent
+	for len(lobby.Players[2].Messages()) > 0 {
+		if event := <-lobby.Players[2].Messages(); event.Type == EventRoster {
+			roster = event.Data.(RosterEvent)
+		}
+	}
+	if roster.Ready != 1 || roster.ReadyNeeded != 2 {
+		t.Errorf("expected the roster to show 1 of 2 ready, got %+v", roster)
+	}
+	lobby.SetPlayerReady("player2", true)
+	if state := lobby.GameStatus().State; state != Starting && state != Started {
+		t.Fatalf("expected the game to start by itself, got state %v", state)
+	}
+
+	lobby = NewGameLobby(1, 0, 0)
+	lobby.ReadyCheck = ReadyCheck{ReadyPolicy: ReadyAll, AutoStart: true}
+	lobby.QuestionPool = func() []*Question { return questions }
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.SetPlayerReady("player1", true)
+	lobby.RemovePlayer("player2")
+	if state := lobby.GameStatus().State; state != Starting && state != Started {
+		t.Fatalf("expected the game to start once the unready player left, got state %v", state)
+	}
+
+	if err := (ReadyCheck{AutoStart: true}).Validate(JoinRules{}); err == nil {
+		t.Error("expected auto start without a ready policy to be refused")
+	}
+	if err := (ReadyCheck{ReadyPolicy: ReadyQuorum}).Validate(JoinRules{}); err == nil {
+		t.Error("expected a quorum of nobody to be refused")
+	}
+	if err := (ReadyCheck{ReadyPolicy: ReadyQuorum, ReadyQuorum: 3}).Validate(JoinRules{MaxPlayers: 2}); err == nil {
+		t.Error("expected a quorum bigger than the lobby can hold to be refused")
+	}
+	if err := (ReadyCheck{ReadyPolicy: ReadyQuorum, ReadyQuorum: 3}).Validate(JoinRules{}); err != nil {
+		t.Errorf("expected any quorum to be fine without a player limit, got %v", err)
+	}
+}
+
+func TestHostRole(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	lobby := NewGameLobby(1, 0, 0)
+	host, _ := lobby.AddPlayer("player1", "")
+	player2, _ := lobby.AddPlayer("player2", "")
+	player3, _ := lobby.AddPlayer("player3", "")
+	if summary, _ := lobby.Host(); summary.ID != host.ID {
+		t.Fatalf("expected whoever joined first to be the host, got %+v", summary)
+	}
+
+	// nobody else gets to do host things.
+	if err := lobby.StartGameAs("player2", questions); !errors.Is(err, ErrNotHost) {
+		t.Errorf("expected only the host to be able to start the game, got %v", err)
+	}
+	if err := lobby.KickPlayer("player2", player3.ID); !errors.Is(err, ErrNotHost) {
+		t.Errorf("expected only the host to be able to kick, got %v", err)
+	}
+	if err := lobby.UpdateSettings("player2", LobbySettings{QuestionCount: 2}); !errors.Is(err, ErrNotHost) {
+		t.Errorf("expected only the host to be able to change settings, got %v", err)
+	}
+	if err := lobby.TransferHost("nobody", player2.ID); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("expected an unknown session to be refused, got %v", err)
+	}
+
+	if err := lobby.KickPlayer("player1", player3.ID); err != nil {
+		t.Fatalf("expected the host to be able to kick, got %v", err)
+	}
+	if len(lobby.Roster()) != 2 {
+		t.Fatalf("expected the kicked player to be gone, got %+v", lobby.Roster())
+	}
+	for range player3.Messages() {
+	} // only finishes because kicking ended their subscription
+
+	if err := lobby.UpdateSettings("player1", LobbySettings{QuestionCount: 2, Countdown: 0, LobbyOptions: LobbyOptions{Scoring: SpeedScoring{}}}); err != nil {
+		t.Fatalf("expected the host to be able to change settings, got %v", err)
+	}
+	var settings SettingsEvent
+	for len(player2.Messages()) > 0 {
+		if event := <-player2.Messages(); event.Type == EventSettings {
+			settings = event.Data.(SettingsEvent)
+		}
+	}
+	if settings.QuestionCount != 2 || settings.Scoring != "speed" || settings.RoundMode != RoundRace {
+		t.Errorf("expected everyone to hear about the new settings, got %+v", settings)
+	}
+
+	// host passes to someone with a websocket when the host's goes away, and to anyone when the host leaves.
+	lobby.Connect("player1")
+	lobby.Connect("player2")
+	lobby.Disconnect("player1")
+	if summary, _ := lobby.Host(); summary.ID != player2.ID {
+		t.Fatalf("expected host to pass when the host disconnected, got %+v", summary)
+	}
+	if err := lobby.TransferHost("player2", host.ID); err != nil {
+		t.Fatalf("expected the new host to be able to hand it back, got %v", err)
+	}
+	lobby.RemovePlayer("player1")
+	if summary, _ := lobby.Host(); summary.ID != player2.ID {
+		t.Fatalf("expected host to pass when the host left, got %+v", summary)
+	}
+
+	if err := lobby.StartGameAs("player2", questions); err != nil {
+		t.Fatalf("expected the host to be able to start the game, got %v", err)
+	}
+	if len(lobby.Questions) != 2 {
+		t.Errorf("expected the game to use the updated question count, got %d questions", len(lobby.Questions))
+	}
+	if err := lobby.UpdateSettings("player2", LobbySettings{QuestionCount: 1}); !errors.Is(err, ErrLobbyNotWaiting) {
+		t.Errorf("expected settings to be fixed once the game started, got %v", err)
+	}
+}
+
+func TestJoinRules(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+	}
+	lobby := NewGameLobby(1, 0, 0)
+	lobby.JoinRules = JoinRules{MaxPlayers: 2, LateJoin: LateJoinSpectate}
+	lobby.Password = "hunter2"
+	lobby.AddPlayer("player1", "")
+	if _, err := lobby.Join("player2", "", "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("expected the wrong password to be refused, got %v", err)
+	}
+	if _, err := lobby.Join("player2", "", "hunter2"); err != nil {
+		t.Fatalf("expected the right password to get in, got %v", err)
+	}
+	if _, err := lobby.Join("player3", "", "hunter2"); !errors.Is(err, ErrLobbyFull) {
+		t.Errorf("expected a third player to be turned away, got %v", err)
+	}
+
+	lobby.StartGame(questions)
+	spectator, err := lobby.Join("player3", "", "hunter2")
+	if err != nil || !spectator.Spectator {
+		t.Fatalf("expected a late joiner to get in as a spectator, got %+v %v", spectator, err)
+	}
+	for lobby.GameStatus().State != Started {
+		time.Sleep(time.Millisecond)
+	}
+	if err, _ := lobby.SubmitAnswer("player3", "q1", 1); !errors.Is(err, ErrSpectator) {
+		t.Errorf("expected a spectator not to be able to answer, got %v", err)
+	}
+	if err := lobby.TransferHost("player1", spectator.ID); !errors.Is(err, ErrSpectator) || !lobby.Players[0].host {
+		t.Errorf("expected a spectator not to be able to take over as host, got %v", err)
+	}
+	if len(lobby.Scoreboard()) != 2 {
+		t.Errorf("expected the spectator to be left off the scoreboard, got %+v", lobby.Scoreboard())
+	}
+	// the spectator is not waited on, so the two players answering finishes the question.
+	lobby.SubmitAnswer("player1", "q1", 0)
+	lobby.SubmitAnswer("player2", "q1", 0)
+	if lobby.GameStatus().State != Ended {
+		t.Error("expected the game to end once both players had answered")
+	}
+
+	lobby = NewGameLobby(1, 0, 0)
+	lobby.AddPlayer("player1", "")
+	lobby.StartGame(questions)
+	if _, err := lobby.Join("player2", "", ""); !errors.Is(err, ErrGameAlreadyStarted) {
+		t.Errorf("expected a late joiner to be rejected by default, got %v", err)
+	}
+
+	if err := (JoinRules{Visibility: "secret"}).Validate(); err == nil {
+		t.Error("expected an unknown visibility to be refused")
+	}
+}
+
+func TestSpectatorsGetTheAnswers(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	lobby := NewGameLobby(2, 0, 0)
+	lobby.AddPlayer("player1", "")
+	spectator := lobby.Spectate()
+	lobby.StartGame(questions)
+	for lobby.GameStatus().State != Started {
+		time.Sleep(time.Millisecond)
+	}
+	player := lobby.Players[0]
+	nextMessage(t, player) // countdown
+	question := nextMessage(t, player).Data.(QuestionEvent).Question
+	lobby.SubmitAnswer("player1", question.ID, lobby.Questions[0].CorrectIndex)
+	if event := nextMessage(t, player); event.Type != EventQuestion {
+		t.Errorf("expected the player to go straight on to the next question, got %+v", event)
+	}
+
+	var answer *AnswerEvent
+	var roster RosterEvent
+	for len(spectator.Events()) > 0 {
+		event := <-spectator.Events()
+		switch data := event.Data.(type) {
+		case AnswerEvent:
+			answer = &data
+		case RosterEvent:
+			roster = data
+		}
+	}
+	if answer == nil || answer.QuestionID != question.ID || answer.CorrectAnswer != lobby.Questions[0].Options[answer.CorrectIndex] {
+		t.Errorf("expected the spectator to be told the answer to the first question, got %+v", answer)
+	}
+	if roster.Spectators != 1 || len(roster.Players) != 1 {
+		t.Errorf("expected the roster to count the spectator apart from the players, got %+v", roster)
+	}
+
+	missed, _ := lobby.ResumeSpectating(0)
+	playerMissed, _, _ := lobby.Resume("player1", 0)
+	if len(missed) != len(playerMissed)+1 {
+		t.Errorf("expected only the spectator to have the answer replayed, got %d and %d events", len(missed), len(playerMissed))
+	}
+
+	lobby.StopSpectating(spectator)
+	if lobby.BroadcastMetrics().Subscribers != 1 {
+		t.Errorf("expected only the player to be left subscribed, got %+v", lobby.BroadcastMetrics())
+	}
+}
+
+func TestLateJoinSpectatorsGetTheAnswers(t *testing.T) {
+	questions := []*Question{{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1}}
+	lobby := NewGameLobby(1, 0, 0)
+	lobby.JoinRules = JoinRules{LateJoin: LateJoinSpectate}
+	lobby.AddPlayer("player1", "")
+	lobby.StartGame(questions)
+	spectator, err := lobby.Join("player2", "", "")
+	if err != nil || !spectator.Spectator {
+		t.Fatalf("expected a late joiner to get in as a spectator, got %+v %v", spectator, err)
+	}
+	for lobby.GameStatus().State != Started {
+		time.Sleep(time.Millisecond)
+	}
+	lobby.SubmitAnswer("player1", "q1", 1)
+
+	gotAnswer := func(player *Player) bool {
+		answered := false
+		for len(player.Messages()) > 0 {
+			if event := <-player.Messages(); event.Type == EventAnswer {
+				answered = true
+			}
+		}
+		return answered
+	}
+	if !gotAnswer(spectator) {
+		t.Error("expected the late joiner to be told the answer")
+	}
+	if gotAnswer(lobby.Players[0]) {
+		t.Error("expected the player not to get the spectator only answer event")
+	}
+	spectatorMissed, _, _ := lobby.Resume("player2", 0)
+	playerMissed, _, _ := lobby.Resume("player1", 0)
+	if len(spectatorMissed) != len(playerMissed)+1 {
+		t.Errorf("expected only the late joiner to have the answer replayed, got %d and %d events", len(spectatorMissed), len(playerMissed))
+	}
+}
+
+func TestSpectatorsAreToldTimedOutAnswersOnce(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	lobby := setupAndStartGame(t, 2, 0, questions)
+	spectator := lobby.Spectate()
+	defer lobby.StopSpectating(spectator)
+
+	lobby.questionTimedOut(0)
+	solutions := 0
+	for len(spectator.Events()) > 0 {
+		switch event := <-spectator.Events(); event.Type {
+		case EventQuestionTimedOut, EventAnswer:
+			solutions++
+		}
+	}
+	if solutions != 1 {
+		t.Errorf("expected the spectator to be told the answer to a timed out question once, got it %d times", solutions)
+	}
+}
+
+func TestQuickMatchAndLobbyBrowser(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+	}
+	lobbies := NewLobbies(time.Minute)
+	settings := LobbySettings{QuestionCount: 1, LobbyOptions: LobbyOptions{QuestionPool: func() []*Question { return questions }}}
+
+	first, _, err := lobbies.QuickMatch(settings, 3, "player1", "")
+	if err != nil {
+		t.Fatalf("failed to quick match: %v", err)
+	}
+	second, _, _ := lobbies.QuickMatch(settings, 3, "player2", "")
+	speedy := settings
+	speedy.Scoring = SpeedScoring{}
+	other, _, _ := lobbies.QuickMatch(speedy, 3, "player3", "")
+	if first != second || first == other {
+		t.Fatalf("expected players wanting the same settings to be matched together, and the others apart")
+	}
+
+	lobbies.AddLobby(1, 0, 0, LobbyOptions{JoinRules: JoinRules{Visibility: Private}}, &Player{SessionID: "hidden"})
+	listed := lobbies.PublicLobbies()
+	if len(listed) != 2 || listed[0].ID != first.ID || listed[0].Players != 2 || !listed[0].QuickMatch || listed[0].Settings.JoinRules.MaxPlayers != 3 {
+		t.Fatalf("expected the two public lobbies, fullest first, got %+v", listed)
+	}
+
+	lobbies.QuickMatch(settings, 3, "player4", "")
+	if state := first.GameStatus().State; state != Starting && state != Started {
+		t.Fatalf("expected the quick match to start once it had 3 players, got state %v", state)
+	}
+	if len(lobbies.PublicLobbies()) != 1 {
+		t.Errorf("expected the started lobby to drop out of the browser, got %+v", lobbies.PublicLobbies())
+	}
+
+	// the next player gets a fresh lobby rather than the started one.
+	next, _, _ := lobbies.QuickMatch(settings, 3, "player5", "")
+	if next == first {
+		t.Error("expected a new lobby once the first one had started")
+	}
+}
+
+func TestRematchSeries(t *testing.T) {
+	questions := []*Question{
+		{ID: "q1", QuestionText: "Question 1", Options: []string{"A", "B", "C"}, CorrectIndex: 1},
+		{ID: "q2", QuestionText: "Question 2", Options: []string{"A", "B", "C"}, CorrectIndex: 2},
+	}
+	lobby := NewGameLobby(1, 0, 0)
+	lobby.AddPlayer("player1", "")
+	player2, _ := lobby.AddPlayer("player2", "")
+	playRound := func() string {
+		t.Helper()
+		if err := lobby.StartGame(questions); err != nil {
+			t.Fatalf("failed to start the round: %v", err)
+		}
+		for lobby.GameStatus().State != Started {
+			time.Sleep(time.Millisecond)
+		}
+		question := lobby.Questions[0]
+		lobby.SubmitAnswer("player2", question.ID, question.CorrectIndex)
+		return question.ID
+	}
+
+	if err := lobby.Rematch("player1"); !errors.Is(err, ErrGameNotEnded) {
+		t.Errorf("expected no rematch before the game has ended, got %v", err)
+	}
+	first := playRound()
+	if err := lobby.Rematch("player2"); !errors.Is(err, ErrNotHost) {
+		t.Errorf("expected only the host to be able to rematch, got %v", err)
+	}
+	if err := lobby.Rematch("player1"); err != nil {
+		t.Fatalf("expected the host to be able to rematch, got %v", err)
+	}
+	if lobby.GameStatus().State != Waiting || len(lobby.Roster()) != 2 || player2.Score != 0 {
+		t.Fatalf("expected the same players back to waiting with fresh scores, got %+v", lobby.GameStatus())
+	}
+
+	second := playRound()
+	if second == first {
+		t.Errorf("expected the rematch not to repeat question %s", first)
+	}
+	rounds, standings := lobby.History()
+	if len(rounds) != 2 || rounds[1].Round != 2 {
+		t.Fatalf("expected two rounds of history, got %+v", rounds)
+	}
+	if standings[0].ID != player2.ID || standings[0].Total != 20 || standings[0].Wins != 2 || standings[0].Rounds != 2 {
+		t.Errorf("expected player2 to lead the series with both wins, got %+v", standings)
+	}
+
+	// every question has been asked now.
+	lobby.Rematch("player1")
+	if err := lobby.StartGame(questions); !errors.Is(err, ErrNotEnoughQuestions) {
+		t.Errorf("expected a third round to run out of questions, got %v", err)
+	}
+}
+
+func TestQuestionTypes(t *testing.T) {
+	// each game is one question for three players.
+	startOne := func(question *Question) *GameLobby {
+		question.Normalize()
+		lobby := NewGameLobby(1, 0, 0)
+		lobby.AddPlayer("player1", "")
+		lobby.AddPlayer("player2", "")
+		lobby.AddPlayer("player3", "")
+		lobby.StartGame([]*Question{question})
+		time.Sleep(time.Millisecond)
+		return lobby
+	}
+
+	trueFalse := startOne(&Question{ID: "tf", QuestionText: "Caps are worn on heads", Type: TrueFalse, CorrectIndex: 0})
+	if public := trueFalse.Questions[0].Public(); public.Type != TrueFalse || len(public.Options) != 2 || public.Options[0] != "True" {
+		t.Fatalf("expected a true/false question to get true and false as its options, got %+v", public)
+	}
+	if err, _ := trueFalse.Submit("player1", "tf", ChoicesAnswer(0)); !errors.Is(err, ErrInvalidAnswer) {
+		t.Errorf("expected an array answer to a true/false question to be refused, got %v", err)
+	}
+	if err, result := trueFalse.Submit("player1", "tf", BoolAnswer(true)); err != nil || !result.Correct {
+		t.Errorf("expected true to be right, got %v %+v", err, result)
+	}
+	// true and false go by what the options say, whichever order they are in.
+	reversed := startOne(&Question{ID: "tf", QuestionText: "Boots are worn on heads", Type: TrueFalse, Options: []string{"False", "True"}, CorrectIndex: 0})
+	if err, result := reversed.Submit("player1", "tf", BoolAnswer(true)); !errors.Is(err, ErrIncorrectAnswer) || result.Correct {
+		t.Errorf("expected true to be wrong when the first option is false, got %v %+v", err, result)
+	}
+	if err, result := reversed.Submit("player2", "tf", BoolAnswer(false)); err != nil || !result.Correct {
+		t.Errorf("expected false to be right when the first option is false, got %v %+v", err, result)
+	}
+	yesNo := startOne(&Question{ID: "tf", QuestionText: "Is a beanie a cap?", Type: TrueFalse, Options: []string{"Yes", "No"}, CorrectIndex: 0})
+	if err, _ := yesNo.Submit("player1", "tf", BoolAnswer(true)); !errors.Is(err, ErrInvalidAnswer) {
+		t.Errorf("expected true to be refused when the options do not say true and false, got %v", err)
+	}
+	if err, result := yesNo.Submit("player1", "tf", ChoiceAnswer(0)); err != nil || !result.Correct {
+		t.Errorf("expected the option index to still work, got %v %+v", err, result)
+	}
+
+	allOrNothing := startOne(&Question{ID: "ms", QuestionText: "Which are caps?", Type: MultiSelect, Options: []string{"Beanie", "Boot", "Beret", "Glove"}, CorrectIndexes: []int{0, 2}})
+	if err, result := allOrNothing.Submit("player1", "ms", ChoicesAnswer(0)); !errors.Is(err, ErrIncorrectAnswer) || result.Points > 0 {
+		t.Errorf("expected half the options to earn nothing without partial credit, got %v %+v", err, result)
+	}
+	if err, _ := allOrNothing.Submit("player2", "ms", ChoicesAnswer(0, 0)); !errors.Is(err, ErrInvalidAnswer) {
+		t.Errorf("expected picking an option twice to be refused, got %v", err)
+	}
+	if err, result := allOrNothing.Submit("player2", "ms", ChoicesAnswer(2, 0)); err != nil || !result.Correct || allOrNothing.State != Ended {
+		t.Errorf("expected every correct option in any order to be right and end the game, got %v %+v", err, result)
+	}
+
+	partial := startOne(&Question{ID: "ms", QuestionText: "Which are caps?", Type: MultiSelect, Options: []string{"Beanie", "Boot", "Beret", "Fedora"}, CorrectIndexes: []int{0, 2, 3}, Credit: CreditPartial})
+	err, result := partial.Submit("player1", "ms", ChoicesAnswer(0, 2))
+	if err != nil || !result.Accepted || result.Correct || !result.LockedOut || result.Points <= 0 || math.Abs(result.Credit-2.0/3) > 0.001 {
+		t.Fatalf("expected two of three options to earn two thirds of the points, got %v %+v", err, result)
+	}
+	if !strings.Contains(result.Explanation, "partial credit") {
+		t.Errorf("expected the explanation to mention partial credit, got %q", result.Explanation)
+	}
+	if partial.CurrentQuestionIndex != 0 || partial.State != Started {
+		t.Fatal("expected partial credit to leave the question open")
+	}
+	if err, _ := partial.Submit("player1", "ms", ChoicesAnswer(0, 2, 3)); !errors.Is(err, ErrAlreadyAnswered) {
+		t.Errorf("expected partial credit to be the player's last go, got %v", err)
+	}
+	if err, result := partial.Submit("player2", "ms", ChoicesAnswer(0, 1)); !errors.Is(err, ErrIncorrectAnswer) || result.Points > 0 {
+		t.Errorf("expected as many wrong options as right ones to earn nothing, got %v %+v", err, result)
+	}
+
+	tolerance := startOne(&Question{ID: "num", QuestionText: "How many caps in the table?", Type: Numeric,
+		Numeric: &NumericAnswer{Value: 100, Rule: WithinTolerance, Bands: []ToleranceBand{{Within: 20, Credit: 0.25}, {Within: 5, Credit: 0.5}}, Unit: "caps"}})
+	if public := tolerance.Questions[0].Public(); public.Unit != "caps" || public.Options == nil || len(public.Options) != 0 {
+		t.Errorf("expected a numeric question to show its unit and no options, got %+v", public)
+	}
+	if err, result := tolerance.Submit("player1", "num", ValueAnswer(110)); err != nil || result.Credit != 0.25 {
+		t.Errorf("expected a guess 10 off to earn a quarter, got %v %+v", err, result)
+	}
+	if err, result := tolerance.Submit("player2", "num", ValueAnswer(97)); err != nil || result.Credit != 0.5 {
+		t.Errorf("expected the best band a guess is in to count, got %v %+v", err, result)
+	}
+	if err, _ := tolerance.Submit("player3", "num", ValueAnswer(150)); !errors.Is(err, ErrIncorrectAnswer) {
+		t.Errorf("expected a guess outside every band to be wrong, got %v", err)
+	}
+}
+
+func TestClosestWithoutGoingOver(t *testing.T) {
+	question := &Question{ID: "num", QuestionText: "How many caps were sold?", Type: Numeric, Numeric: &NumericAnswer{Value: 1000, Rule: ClosestWithoutOver}}
+	lobby := NewGameLobby(1, 0, 0)
+	lobby.AddPlayer("player1", "")
+	lobby.AddPlayer("player2", "")
+	lobby.AddPlayer("player3", "")
+	lobby.StartGame([]*Question{question})
+	time.Sleep(time.Millisecond)
+
+	if err, _ := lobby.Submit("player1", "num", ChoicesAnswer(1)); !errors.Is(err, ErrInvalidAnswer) {
+		t.Fatalf("expected a guess that isn't a number to be refused, got %v", err)
+	}
+	// guesses are locked in even when racing, since nobody knows who is closest until everyone has guessed.
+	if err, result := lobby.Submit("player1", "num", ValueAnswer(950)); err != nil || !result.Accepted || result.Points != 0 {
+		t.Fatalf("expected the guess to be locked in, got %v %+v", err, result)
+	}
+	lobby.Submit("player2", "num", ValueAnswer(990))
+	if lobby.State != Started {
+		t.Fatal("expected the question to stay open until everyone has guessed")
+	}
+	lobby.Submit("player3", "num", ValueAnswer(1010))
+	if lobby.State != Ended {
+		t.Fatal("expected the last guess to close the question")
+	}
+	if lobby.Players[0].Score != 0 || lobby.Players[1].Score <= 0 || lobby.Players[2].Score != 0 {
+		t.Errorf("expected only the closest guess without going over to score, got %d %d %d",
+			lobby.Players[0].Score, lobby.Players[1].Score, lobby.Players[2].Score)
+	}
+
+	var reveal *RevealEvent
+	for len(lobby.Players[0].Messages()) > 0 {
+		if event := <-lobby.Players[0].Messages(); event.Type == EventReveal {
+			r := event.Data.(RevealEvent)
+			reveal = &r
+		}
+	}
+	if reveal == nil || reveal.CorrectIndex != -1 || reveal.CorrectValue == nil || *reveal.CorrectValue != 1000 ||
+		len(reveal.Correct) != 1 || reveal.Correct[0].ID != lobby.Players[1].ID {
+		t.Errorf("unexpected reveal %+v", reveal)
+	}
+}
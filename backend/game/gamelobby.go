@@ -1,17 +1,21 @@
 package game
 
 import (
-	"errors"
+	"fmt"
+	"github.com/google/uuid"
 	"log"
-	"math/rand"
+	"math"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 type GameStatusResult struct {
-	State        GameState `json:"state"`
-	WinningScore int       `json:"winningScore"`
-	Winners      []string  `json:"winners"` // Session IDs of the winning player(s)
+	State        GameState         `json:"state"`
+	WinningScore int               `json:"winningScore"`
+	Winners      []PlayerSummary   `json:"winners"` // Public ids and names of the winning player(s), session ids are secret.
+	Scoreboard   []ScoreboardEntry `json:"scoreboard"`
 }
 
 type GameState int
@@ -23,25 +27,101 @@ const (
 	Ended
 )
 
+// LobbyOptions are the optional settings a lobby is created with, on top of the question count and timings.
+type LobbyOptions struct {
+	QuestionFilter QuestionFilter
+	Scoring        ScoringStrategy // nil means FlatScoring
+	RoundMode      RoundMode       // blank means RoundRace
+	AnswerRules    AnswerRules
+	ReadyCheck     ReadyCheck
+	JoinRules      JoinRules
+	Password       string             // needed to Join, if not blank
+	QuestionBank   string             // name of the bank QuestionPool draws from, blank for the server's default
+	QuestionPool   func() []*Question // where the questions come from, including when the game starts by itself, see ReadyCheck.AutoStart
+}
+
 type GameLobby struct {
 	mutex                sync.Mutex
+	ID                   string
 	QuestionCount        int
 	Countdown            int // milliseconds
+	QuestionTimeout      int // milliseconds allowed per question before moving on, 0 means no time limit.
 	State                GameState
 	Players              []*Player
 	CurrentQuestionIndex int
 	Questions            []*Question
 	LastGameInteraction  time.Time
+	broadcaster          *Broadcaster
+	analytics            AnalyticsSink
+	outbox               []*Event       // events published while holding the mutex, handed to the broadcaster by unlock
+	flushMutex           sync.Mutex     // keeps outboxes going to the broadcaster in the order they were filled
+	eventSeq             uint64         // sequence number of the last event published to the lobby
+	eventLog             []*Event       // recent events, oldest first, for replaying to players who reconnect
+	questionSentAt       time.Time      // when the current question went out to the players, for measuring how quickly they answer.
+	correctAnswers       int            // how many players have got the current question right
+	lockedAnswers        []lockedAnswer // answers to the current question waiting to be revealed, in RoundEveryone
+	spectators           int            // how many spectator subscriptions there are, see Spectate
+	matchKey             string         // which quick match players this lobby is for, blank if it was not made by QuickMatch
+	targetSize           int            // how many players a quick match lobby starts the game with
+	history              []RoundResult  // every game the lobby has finished, see Rematch
+	questionTimer        *time.Timer
+	LobbyOptions
 }
 
-func NewGameLobby(questionCount, countdown int) *GameLobby {
+func NewGameLobby(questionCount, countdown, questionTimeout int) *GameLobby {
 	return &GameLobby{
 		QuestionCount:        questionCount,
 		Countdown:            countdown,
+		QuestionTimeout:      questionTimeout,
 		State:                Waiting,
 		Players:              make([]*Player, 0),
 		CurrentQuestionIndex: 0,
+		broadcaster:          NewBroadcaster(),
+		analytics:            noAnalytics{},
+	}
+}
+
+// unlock releases the lobby mutex and then hands any events published while it was held over to the broadcaster,
+// so nothing is ever delivered while the lobby is locked.
+// the flush mutex is taken before letting go of the lobby so that one holder's events are all out before the next holder's.
+func (g *GameLobby) unlock() {
+	events := g.outbox
+	g.outbox = nil
+	g.flushMutex.Lock()
+	g.mutex.Unlock()
+	if len(events) > 0 {
+		g.broadcaster.Publish(events...)
 	}
+	g.flushMutex.Unlock()
+}
+
+// Connect returns the subscription that a player's websocket should read events from.
+// every Connect should be paired with a Disconnect once the websocket is done with.
+// if the player's previous subscription was dropped for falling too far behind they get a fresh one,
+// and it is up to the client to resume from the last event it saw to fill in the gap.
+// a player only reads events from one websocket at a time, so a second one, like after reloading the page,
+// takes over with a fresh subscription and the old websocket is closed by its subscription ending.
+func (g *GameLobby) Connect(sessionID string) (*Subscription, error) {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	player, err := g.GetPlayer(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if player.connections > 0 {
+		g.broadcaster.Unsubscribe(player.subscription)
+	}
+	if !g.broadcaster.Subscribed(player.subscription) {
+		player.subscription = g.subscribe(player.Spectator)
+	}
+	player.connections++
+	return player.subscription, nil
+}
+
+// BroadcastMetrics reports on event delivery to the lobby's players.
+func (g *GameLobby) BroadcastMetrics() BroadcastMetrics {
+	return g.broadcaster.Metrics()
 }
 
 func (g *GameLobby) SetLastGameInteraction() {
@@ -54,74 +134,222 @@ func (g *GameLobby) GetPlayer(sessionID string) (*Player, error) {
 			return p, nil
 		}
 	}
-	return nil, errors.New("player not found")
+	return nil, ErrPlayerNotFound
 }
 
-func (g *GameLobby) setShuffledQuestionsFromPool(questions []*Question) {
-	shuffled := make([]*Question, len(questions))
-	copy(shuffled, questions)
-	rand.Shuffle(len(shuffled), func(i, j int) {
-		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
-	})
-
-	// If count is 0 or exceeds the length of the question pool, use the entire shuffled pool
-	if g.QuestionCount == 0 || g.QuestionCount > len(shuffled) {
-		g.Questions = shuffled
-	} else {
-		// Use the first 'g.QuestionCount' elements of the shuffled slice
-		g.Questions = shuffled[:g.QuestionCount]
+func (g *GameLobby) setShuffledQuestionsFromPool(questions []*Question) error {
+	// a rematch never asks what an earlier round in the lobby already asked.
+	selected, err := g.QuestionFilter.Select(g.unusedQuestions(questions), g.QuestionCount)
+	if err != nil {
+		return err
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("%w, there are none left that this lobby has not already asked", ErrNotEnoughQuestions)
 	}
+	g.Questions = selected
+	return nil
 }
 
-func (g *GameLobby) AddPlayer(sessionID string) error {
+// AddPlayer puts a new player into the lobby under the given display name, or a generated one if the name is blank.
+// display names have to be unique within the lobby so that players can tell each other apart.
+// this is for the lobby's creator and anything else that needs no password, everyone else gets in with Join.
+func (g *GameLobby) AddPlayer(sessionID, name string) (*Player, error) {
 	g.mutex.Lock()
-	defer g.mutex.Unlock()
+	defer g.unlock()
 
 	if g.State != Waiting {
-		return errors.New("cannot add player, lobby is not in waiting state")
+		return nil, fmt.Errorf("cannot add player, %w", ErrLobbyNotWaiting)
+	}
+	return g.addPlayer(sessionID, name, false)
+}
+
+// addPlayer does the adding for AddPlayer and Join. spectators skip the capacity check, they only watch.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) addPlayer(sessionID, name string, spectator bool) (*Player, error) {
+	if !spectator && g.full() {
+		return nil, fmt.Errorf("cannot add player, %w", ErrLobbyFull)
 	}
 	// Check if the sessionID is already in the list of players
 	for _, player := range g.Players {
 		if player.SessionID == sessionID {
-			return errors.New("player with this sessionID is already added")
+			return nil, ErrPlayerAlreadyAdded
 		}
 	}
 
-	g.Players = append(g.Players, &Player{
+	name, err := ValidatePlayerName(name)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = g.defaultPlayerName()
+	} else if g.playerNameTaken(name) {
+		return nil, ErrPlayerNameTaken
+	}
+
+	player := &Player{
+		ID:                uuid.New().String(),
 		SessionID:         sessionID,
+		Name:              name,
 		Score:             0,
 		QuestionsAnswered: []string{},
-		MessageChannel:    make(chan Message, g.QuestionCount+2), // Plus 2 for start and finish messages
-	})
+		subscription:      g.subscribe(spectator), // so events queue up for the player even before their websocket connects.
+		Spectator:         spectator,
+		// whoever gets into an empty lobby first is in charge of it.
+		host: !spectator && g.hostPlayer() == nil,
+	}
+	g.Players = append(g.Players, player)
 	g.SetLastGameInteraction()
+	g.sendRoster()
+	return player, nil
+}
+
+// RemovePlayer takes a player out of the lobby and ends their subscription, which also ends their websocket.
+func (g *GameLobby) RemovePlayer(sessionID string) error {
+	g.mutex.Lock()
+	defer g.unlock()
 
-	// TODO dont forget to close(player.MessageChannel) at some point (how about when the game is done?)
+	player, err := g.GetPlayer(sessionID)
+	if err != nil {
+		return err
+	}
+	g.removePlayer(player)
 	return nil
 }
 
+// removePlayer does the removing for RemovePlayer and KickPlayer. must be called while holding the lobby mutex.
+func (g *GameLobby) removePlayer(player *Player) {
+	for i, p := range g.Players {
+		if p == player {
+			g.Players = append(g.Players[:i], g.Players[i+1:]...)
+			break
+		}
+	}
+	g.broadcaster.Unsubscribe(player.subscription)
+	if player.host {
+		g.passHost(player, false)
+	}
+	g.SetLastGameInteraction()
+	g.sendRoster()
+
+	// whoever is left may have all answered already, in which case nobody is waiting on the player who left.
+	if g.State == Started && len(g.contestants()) > 0 {
+		g.allPlayersAnswered(g.Questions[g.CurrentQuestionIndex].ID)
+	}
+	// or they may all be ready, having only been waiting on the player who left.
+	g.autoStartIfReady()
+}
+
+// Roster returns the public view of everyone in the lobby, in the order they joined.
+func (g *GameLobby) Roster() []PlayerSummary {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.roster()
+}
+
+func (g *GameLobby) roster() []PlayerSummary {
+	roster := make([]PlayerSummary, 0, len(g.Players))
+	for _, player := range g.Players {
+		roster = append(roster, player.Summary())
+	}
+	return roster
+}
+
+// sendRoster lets everyone know who is in the lobby now. must be called while holding the lobby mutex.
+func (g *GameLobby) sendRoster() {
+	ready, needed := g.readyCount()
+	g.publish(EventRoster, RosterEvent{Players: g.roster(), Ready: ready, ReadyNeeded: needed, Spectators: g.spectators})
+}
+
+// SetPlayerReady records whether a player is ready to play and lets everyone know.
+func (g *GameLobby) SetPlayerReady(sessionID string, ready bool) error {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	player, err := g.GetPlayer(sessionID)
+	if err != nil {
+		return err
+	}
+	if player.Spectator {
+		return fmt.Errorf("%w, they are never ready", ErrSpectator)
+	}
+	player.Ready = ready
+	g.SetLastGameInteraction()
+	g.sendRoster()
+	g.autoStartIfReady()
+	return nil
+}
+
+// SendChat passes a chat message from one player along to everyone in the lobby, including whoever sent it.
+func (g *GameLobby) SendChat(sessionID, text string) error {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	player, err := g.GetPlayer(sessionID)
+	if err != nil {
+		return err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("%w, it is empty", ErrInvalidChat)
+	}
+	if utf8.RuneCountInString(text) > maxChatLength {
+		return fmt.Errorf("%w, it is too long", ErrInvalidChat)
+	}
+
+	g.publish(EventChat, ChatEvent{From: player.Summary(), Text: text})
+	return nil
+}
+
+func (g *GameLobby) playerNameTaken(name string) bool {
+	for _, player := range g.Players {
+		if strings.EqualFold(player.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *GameLobby) defaultPlayerName() string {
+	for n := len(g.Players) + 1; ; n++ {
+		name := fmt.Sprintf("Player %d", n)
+		if !g.playerNameTaken(name) {
+			return name
+		}
+	}
+}
+
+// StartGame starts the countdown to the first question, once the lobby's ready check passes.
 func (g *GameLobby) StartGame(questionPool []*Question) error {
 	g.mutex.Lock()
+	defer g.unlock()
 	if g.State != Waiting {
-		return errors.New("Game already started")
+		return ErrGameAlreadyStarted
 	}
-	g.State = Starting
+	if err := g.checkReady(); err != nil {
+		return err
+	}
+	return g.startGame(questionPool)
+}
 
+// startGame does the starting for StartGame and autoStartIfReady. must be called while holding the lobby mutex.
+func (g *GameLobby) startGame(questionPool []*Question) error {
 	// Set the shuffled questions for the game
-	g.setShuffledQuestionsFromPool(questionPool)
+	if err := g.setShuffledQuestionsFromPool(questionPool); err != nil {
+		return err
+	}
+	g.State = Starting
 	g.SetLastGameInteraction()
-	g.mutex.Unlock()
+	g.record(AnalyticsEvent{Type: AnalyticsGameStarted})
+	// Notification mechanism to connected clients - inform them that the game is about to start
+	g.publish(EventCountdown, CountdownEvent{CountdownMs: g.Countdown})
 
+	// the countdown runs without the lock, this only gets the lobby back once whoever started the game lets go of it.
 	go func() {
-		// Notification mechanism to connected clients - inform them that the game is about to start
-		for _, player := range g.Players {
-			player.SendMessage(map[string]interface{}{
-				"countdownMs": g.Countdown,
-			})
-		}
 		time.Sleep(time.Duration(g.Countdown) * time.Millisecond)
 		g.mutex.Lock()
+		defer g.unlock()
 		g.State = Started
-		g.mutex.Unlock()
+		g.startQuestionTimer()
 
 		// and how exactly is the question getting in front of the player now? (channels and websockets of course!)
 		g.sendCurrentQuestion()
@@ -132,41 +360,104 @@ func (g *GameLobby) StartGame(questionPool []*Question) error {
 func (g *GameLobby) sendCurrentQuestion() {
 	question := g.Questions[g.CurrentQuestionIndex]
 	log.Printf("sending next question to all players ... question id is: %s", question.ID)
-	questionForPlayer := map[string]interface{}{ //suppress the correct answer.
-		"id":           question.ID,
-		"options":      question.Options,
-		"questionText": question.QuestionText,
+	g.questionSentAt = time.Now()
+	g.correctAnswers = 0
+	g.lockedAnswers = nil
+	g.publish(EventQuestion, g.currentQuestionEvent())
+}
+
+// startQuestionTimer arms the per-question time limit for the current question, if the lobby has one.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) startQuestionTimer() {
+	g.stopQuestionTimer()
+	if g.QuestionTimeout <= 0 {
+		return
 	}
-	for _, player := range g.Players {
-		player.SendMessage(map[string]interface{}{
-			"question": questionForPlayer,
-		})
+	questionIndex := g.CurrentQuestionIndex
+	g.questionTimer = time.AfterFunc(time.Duration(g.QuestionTimeout)*time.Millisecond, func() {
+		g.questionTimedOut(questionIndex)
+	})
+}
+
+func (g *GameLobby) stopQuestionTimer() {
+	if g.questionTimer != nil {
+		g.questionTimer.Stop()
+		g.questionTimer = nil
 	}
 }
 
-func (g *GameLobby) sendGameOver() {
-	for _, player := range g.Players {
-		player.SendMessage(map[string]bool{
-			"gameOver": true,
-		})
+// questionTimedOut moves the game along when nobody answered the question at questionIndex correctly in time.
+// the timer may fire just as someone else advances the question, so if we are no longer on that question there is nothing to do.
+func (g *GameLobby) questionTimedOut(questionIndex int) {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	if g.State != Started || g.CurrentQuestionIndex != questionIndex {
+		return
+	}
+
+	question := g.Questions[questionIndex]
+	log.Printf("question id %s timed out after %dms", question.ID, g.QuestionTimeout)
+	if g.everyoneAnswers() {
+		// the reveal says what the answer was, and scores whoever locked in before the time ran out.
+		g.revealAnswers()
+		return
+	}
+	g.publish(EventQuestionTimedOut, QuestionTimedOutEvent{
+		QuestionID: question.ID,
+		Solution:   question.solution(),
+	})
+	if g.correctAnswers == 0 {
+		g.record(AnalyticsEvent{Type: AnalyticsQuestionSkipped, QuestionID: question.ID})
 	}
+	g.setNextQuestionOrEndGame()
+}
 
+func (g *GameLobby) currentQuestionEvent() QuestionEvent {
+	return QuestionEvent{
+		Index:         g.CurrentQuestionIndex,
+		QuestionCount: len(g.Questions),
+		TimeoutMs:     g.QuestionTimeout,
+		RoundMode:     g.questionRoundMode(),
+		Question:      g.Questions[g.CurrentQuestionIndex].Public(), //suppress the correct answer.
+	}
 }
 
-func (g *GameLobby) SubmitAnswer(playerSessionID string, questionID string, answerIndex int) (error, int) {
+func (g *GameLobby) sendGameOver() {
+	status := g.gameStatus()
+	g.recordGameEnded(status)
+	g.recordRound(status)
+	g.publish(EventGameOver, GameOverEvent{
+		WinningScore: status.WinningScore,
+		Winners:      status.Winners,
+		Scoreboard:   status.Scoreboard,
+		Round:        len(g.history),
+		Standings:    g.standings(),
+	})
+}
+
+// SubmitAnswer answers a single choice or true/false question with the index of an option, see Submit.
+func (g *GameLobby) SubmitAnswer(playerSessionID string, questionID string, answerIndex int) (error, AnswerResult) {
+	return g.Submit(playerSessionID, questionID, ChoiceAnswer(answerIndex))
+}
+
+// Submit records a player's answer to the current question, marking it by the question's type, scoring it with the lobby's
+// scoring strategy and applying its answer rules. a wrong answer comes back as an error along with the result saying what it cost.
+// an answer that earns partial credit scores its share of the points and is the player's last go at the question, without an error.
+func (g *GameLobby) Submit(playerSessionID string, questionID string, answer Answer) (error, AnswerResult) {
 	g.mutex.Lock()
-	defer g.mutex.Unlock()
+	defer g.unlock()
 
 	if g.State == Ended {
-		return errors.New("game has already ended"), 0
+		return ErrGameEnded, AnswerResult{}
 	}
 	if g.State != Started {
-		return errors.New("game is not started"), 0
+		return ErrGameNotStarted, AnswerResult{}
 	}
 
 	currentQuestion := g.Questions[g.CurrentQuestionIndex]
 	if questionID != currentQuestion.ID {
-		return errors.New("incorrect question ID"), 0 //should only be trying to answer the question that is currently in front of all players.
+		return ErrWrongQuestion, AnswerResult{} //should only be trying to answer the question that is currently in front of all players.
 	}
 
 	// Find the player
@@ -179,59 +470,192 @@ func (g *GameLobby) SubmitAnswer(playerSessionID string, questionID string, answ
 	}
 
 	if player == nil {
-		return errors.New("player not found"), 0
+		return ErrPlayerNotFound, AnswerResult{}
+	}
+	if player.Spectator {
+		return fmt.Errorf("%w, they cannot answer", ErrSpectator), AnswerResult{}
 	}
 	// If this player already recorded an answer for this question, then reject this answer.
 	if player.HasAnsweredQuestion(questionID) {
-		return errors.New("player already answered this question"), 0
+		return ErrAlreadyAnswered, AnswerResult{Score: player.Score, LockedOut: true}
+	}
+	if g.lockedOut(player) {
+		return fmt.Errorf("%w, locked out after %d wrong answers", ErrAlreadyAnswered, player.wrongAnswers),
+			AnswerResult{Score: player.Score, WrongAnswers: player.wrongAnswers, LockedOut: true}
 	}
 
-	// Record the fact that this player answered this question.
-	player.QuestionsAnswered = append(player.QuestionsAnswered, questionID)
+	if g.everyoneAnswers() {
+		return g.lockInAnswer(player, currentQuestion, answer)
+	}
+	answer, err := currentQuestion.checkAnswer(answer, false)
+	if err != nil {
+		return err, AnswerResult{Score: player.Score}
+	}
+
+	player.startAttempt(questionID)
+	if wait := time.Until(player.retryAt); wait > 0 {
+		return fmt.Errorf("%w, try again in %dms", ErrAnswerCooldown, wait.Milliseconds()), AnswerResult{
+			Score:        player.Score,
+			WrongAnswers: player.wrongAnswers,
+			RetryAfterMs: wait.Milliseconds(),
+		}
+	}
+
+	grade := currentQuestion.grade(answer)
+	correct := grade.correct()
+	latency := time.Since(g.questionSentAt)
+	player.recordAnswer(correct, latency)
+	answered := AnalyticsEvent{
+		Type:        AnalyticsAnswerSubmitted,
+		PlayerID:    player.ID,
+		QuestionID:  questionID,
+		AnswerIndex: grade.choice,
+		Correct:     correct,
+		LatencyMs:   latency.Milliseconds(),
+	}
 
 	// Validate the answer
-	if answerIndex != currentQuestion.CorrectIndex {
+	if grade.credit == 0 {
+		result, penalty := g.wrongAnswer(player, questionID)
+		answered.Points = result.Points
+		g.record(answered)
+		g.sendScores(penalty)
+		if !result.LockedOut {
+			return ErrIncorrectAnswer, result
+		}
+		// Record the fact that this player is done with this question.
+		player.QuestionsAnswered = append(player.QuestionsAnswered, questionID)
 		if !g.allPlayersAnswered(questionID) {
-			return errors.New("incorrect answer"), 0
+			return ErrIncorrectAnswer, result
 		} else {
-			return errors.New("incorrect answer (from all players now)"), 0
+			return fmt.Errorf("%w (from all players now)", ErrIncorrectAnswer), result
 		}
 	}
 
-	// Answer is correct, update player's score
-	// using the same number of points that was coded in the original http handler for correct answer.
-	awardedPoints := 10
-	player.Score += awardedPoints
+	// Answer is correct, or right enough for partial credit, update player's score
+	player.QuestionsAnswered = append(player.QuestionsAnswered, questionID)
+	if correct {
+		g.correctAnswers++
+	}
+	award := g.award(player, currentQuestion, latency, grade)
+	player.Score += award.Points
+	answered.Points = award.Points
+	g.record(answered)
+	g.sendScores(&award)
+	result := AnswerResult{
+		Accepted:     true,
+		Correct:      correct,
+		Points:       award.Points,
+		Explanation:  award.Explanation,
+		Score:        player.Score,
+		WrongAnswers: player.wrongAnswers,
+		LockedOut:    !correct,
+	}
+	if !correct {
+		result.Credit = grade.credit
+	}
 
 	// Check if the game has ended and update its state if so.
-	g.setNextQuestionOrEndGame()
-	return nil, awardedPoints
+	// partial credit leaves the question open for someone to get it fully right.
+	if correct && !g.scoring().EveryoneScores() {
+		g.sendAnswer()
+		g.setNextQuestionOrEndGame()
+	} else {
+		g.allPlayersAnswered(questionID)
+	}
+	return nil, result
+}
+
+// award works out the points for an answer that earned some credit, using the lobby's scoring strategy
+// and scaling them down for partial credit. must be called while holding the lobby mutex, after counting the answer if it is correct.
+func (g *GameLobby) award(player *Player, question *Question, latency time.Duration, grade grade) ScoreAward {
+	position := g.correctAnswers
+	if !grade.correct() {
+		position++ // where it would have come if it were right
+	}
+	award := g.scoring().Score(ScoringContext{
+		Question: question,
+		Latency:  latency,
+		Window:   g.scoringWindow(),
+		Position: position,
+	})
+	if !grade.correct() {
+		full := award.Points
+		award.Points = int(math.Round(float64(full) * grade.credit))
+		award.Explanation = fmt.Sprintf("partial credit, %s: %.0f%% of %d = %d points", grade.detail, grade.credit*100, full, award.Points)
+	}
+	award.PlayerID = player.ID
+	award.QuestionID = question.ID
+	return award
 }
 
 func (g *GameLobby) allPlayersAnswered(questionID string) bool {
 	allPlayersAnswered := true
-	for _, p := range g.Players {
-		if !p.HasAnsweredQuestion(questionID) {
+	for _, p := range g.contestants() {
+		// players locked out of the rest of the game are not waited on.
+		if !p.HasAnsweredQuestion(questionID) && !g.lockedOut(p) {
 			allPlayersAnswered = false
 			break
 		}
 	}
+	if allPlayersAnswered && g.everyoneAnswers() {
+		g.revealAnswers()
+		return true
+	}
 	if allPlayersAnswered {
+		// the question only counts as skipped if nobody got it, which can happen either way when everyone gets to score.
+		if g.correctAnswers == 0 {
+			g.record(AnalyticsEvent{Type: AnalyticsQuestionSkipped, QuestionID: questionID})
+		}
+		g.sendAnswer()
 		g.setNextQuestionOrEndGame()
 	}
 	return allPlayersAnswered
 }
 
+// questionRoundMode is how the current question is being played, which is the lobby's round mode
+// unless the question has to be locked in, see Question.lockedIn.
+func (g *GameLobby) questionRoundMode() RoundMode {
+	if g.everyoneAnswers() {
+		return RoundEveryone
+	}
+	return g.roundMode()
+}
+
+func (g *GameLobby) roundMode() RoundMode {
+	if g.RoundMode == "" {
+		return RoundRace
+	}
+	return g.RoundMode
+}
+
+func (g *GameLobby) scoring() ScoringStrategy {
+	if g.Scoring == nil {
+		return FlatScoring{}
+	}
+	return g.Scoring
+}
+
+// scoringWindow is the time the speed based scoring strategies measure answers against.
+func (g *GameLobby) scoringWindow() time.Duration {
+	if g.QuestionTimeout > 0 {
+		return time.Duration(g.QuestionTimeout) * time.Millisecond
+	}
+	return defaultScoringWindow
+}
+
 func (g *GameLobby) setNextQuestionOrEndGame() {
 	g.SetLastGameInteraction()
-	// Increment the current question index or end the game if all questions are answered
-	if g.CurrentQuestionIndex < len(g.Questions)-1 {
+	// Increment the current question index or end the game if all questions are answered, or nobody is left who can answer them
+	if g.CurrentQuestionIndex < len(g.Questions)-1 && !g.everyoneLockedOut() {
 		g.CurrentQuestionIndex++
+		g.startQuestionTimer()
 		g.sendCurrentQuestion()
 	} else {
 		// This was the last question, so end the game
 		g.CurrentQuestionIndex = 0
 		g.State = Ended
+		g.stopQuestionTimer()
 		g.sendGameOver()
 	}
 }
@@ -239,24 +663,27 @@ func (g *GameLobby) setNextQuestionOrEndGame() {
 func (g *GameLobby) GameStatus() GameStatusResult {
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
+	return g.gameStatus()
+}
 
+func (g *GameLobby) gameStatus() GameStatusResult {
 	var result GameStatusResult
 	result.State = g.State
-	result.WinningScore = 0
-	scoreToSessions := make(map[int][]string) // Map scores to session IDs
+	scoreToPlayers := make(map[int][]PlayerSummary) // Map scores to players
 
-	for _, player := range g.Players {
+	for i, player := range g.contestants() {
 		score := player.Score
-		scoreToSessions[score] = append(scoreToSessions[score], player.SessionID)
+		scoreToPlayers[score] = append(scoreToPlayers[score], player.Summary())
 
-		// Update the high score if this player's score is higher
-		if score > result.WinningScore {
+		// Update the high score if this player's score is higher. penalties can leave everyone below zero, so start from the first player.
+		if i == 0 || score > result.WinningScore {
 			result.WinningScore = score
 		}
 	}
 
-	// Collect session IDs of all players with the high score
-	result.Winners = scoreToSessions[result.WinningScore]
+	// Collect all players with the high score
+	result.Winners = scoreToPlayers[result.WinningScore]
+	result.Scoreboard = g.scoreboard()
 
 	return result
 }
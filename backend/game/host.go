@@ -0,0 +1,223 @@
+package game
+
+import "fmt"
+
+// the host is the player in charge of the lobby. only they can start the game, kick players and change the settings.
+// whoever gets into the lobby first is the host, and it passes on to someone else when they leave or their websocket goes away.
+
+// LobbySettings is everything about a lobby that the host can change while it is waiting for the game to start.
+type LobbySettings struct {
+	QuestionCount   int
+	Countdown       int // milliseconds
+	QuestionTimeout int // milliseconds, 0 means no time limit
+	LobbyOptions
+}
+
+// Summary is how the settings are shown to players, using the same names the lobby was created with.
+func (s LobbySettings) Summary() SettingsEvent {
+	scoring, roundMode := s.Scoring, s.RoundMode
+	if scoring == nil {
+		scoring = FlatScoring{}
+	}
+	if roundMode == "" {
+		roundMode = RoundRace
+	}
+	return SettingsEvent{
+		QuestionCount:     s.QuestionCount,
+		CountdownMs:       s.Countdown,
+		QuestionTimeoutMs: s.QuestionTimeout,
+		QuestionFilter:    s.QuestionFilter,
+		Scoring:           scoring.Name(),
+		RoundMode:         roundMode,
+		AnswerRules:       s.AnswerRules,
+		ReadyCheck:        s.ReadyCheck,
+		JoinRules:         s.JoinRules,
+		PasswordProtected: s.Password != "",
+		QuestionBank:      s.QuestionBank,
+	}
+}
+
+// Host returns the public view of the lobby's host, false if the lobby is empty.
+func (g *GameLobby) Host() (PlayerSummary, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	host := g.hostPlayer()
+	if host == nil {
+		return PlayerSummary{}, false
+	}
+	return host.Summary(), true
+}
+
+func (g *GameLobby) hostPlayer() *Player {
+	for _, player := range g.Players {
+		if player.host {
+			return player
+		}
+	}
+	return nil
+}
+
+// authorizeHost finds the player with the session, as long as they are the host. must be called while holding the lobby mutex.
+func (g *GameLobby) authorizeHost(sessionID string) (*Player, error) {
+	player, err := g.GetPlayer(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !player.host {
+		return nil, ErrNotHost
+	}
+	return player, nil
+}
+
+// passHost hands the host role on from the given player to whoever has been in the lobby longest with a websocket open.
+// if nobody has one open it goes to whoever has been in the longest, unless connectedOnly, in which case the host keeps it.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) passHost(from *Player, connectedOnly bool) bool {
+	var next *Player
+	for _, player := range g.contestants() {
+		if player == from {
+			continue
+		}
+		if player.connections > 0 {
+			next = player
+			break
+		}
+		if next == nil && !connectedOnly {
+			next = player
+		}
+	}
+	if next == nil {
+		return false
+	}
+	from.host = false
+	next.host = true
+	return true
+}
+
+// StartGameAs starts the game like StartGame, on behalf of the player with the session, who has to be the host.
+func (g *GameLobby) StartGameAs(sessionID string, questionPool []*Question) error {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	if _, err := g.authorizeHost(sessionID); err != nil {
+		return fmt.Errorf("cannot start the game, %w", err)
+	}
+	if g.State != Waiting {
+		return ErrGameAlreadyStarted
+	}
+	if err := g.checkReady(); err != nil {
+		return err
+	}
+	return g.startGame(questionPool)
+}
+
+// KickPlayer lets the host remove another player from the lobby by their public id. the host kicking themselves just leaves.
+func (g *GameLobby) KickPlayer(hostSessionID, playerID string) error {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	if _, err := g.authorizeHost(hostSessionID); err != nil {
+		return fmt.Errorf("cannot kick, %w", err)
+	}
+	player := g.playerByID(playerID)
+	if player == nil {
+		return ErrPlayerNotFound
+	}
+	g.removePlayer(player)
+	return nil
+}
+
+// TransferHost lets the host hand the role to another player by their public id. spectators cannot be host, they are only watching.
+func (g *GameLobby) TransferHost(hostSessionID, playerID string) error {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	host, err := g.authorizeHost(hostSessionID)
+	if err != nil {
+		return fmt.Errorf("cannot transfer host, %w", err)
+	}
+	player := g.playerByID(playerID)
+	if player == nil {
+		return ErrPlayerNotFound
+	}
+	if player.Spectator {
+		return fmt.Errorf("cannot transfer host, %w", ErrSpectator)
+	}
+	host.host = false
+	player.host = true
+	g.SetLastGameInteraction()
+	g.sendRoster()
+	return nil
+}
+
+// Disconnect is the other half of Connect, for when a player's websocket goes away.
+// if that leaves the host without a websocket, being host passes to someone who still has one.
+func (g *GameLobby) Disconnect(sessionID string) {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	player, err := g.GetPlayer(sessionID)
+	if err != nil {
+		return // they already left
+	}
+	if player.connections > 0 {
+		player.connections--
+	}
+	if player.host && player.connections == 0 && g.passHost(player, true) {
+		g.sendRoster()
+	}
+}
+
+// Settings returns what the lobby is currently set up with.
+func (g *GameLobby) Settings() LobbySettings {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.settings()
+}
+
+func (g *GameLobby) settings() LobbySettings {
+	return LobbySettings{
+		QuestionCount:   g.QuestionCount,
+		Countdown:       g.Countdown,
+		QuestionTimeout: g.QuestionTimeout,
+		LobbyOptions:    g.LobbyOptions,
+	}
+}
+
+// UpdateSettings lets the host replace the lobby's settings before the game starts, and lets everyone know what they are now.
+// the settings are expected to have been validated already, the same way as when creating a lobby.
+// a nil QuestionPool keeps the one the lobby already has.
+func (g *GameLobby) UpdateSettings(hostSessionID string, settings LobbySettings) error {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	if _, err := g.authorizeHost(hostSessionID); err != nil {
+		return fmt.Errorf("cannot change settings, %w", err)
+	}
+	if g.State != Waiting {
+		return fmt.Errorf("cannot change settings, %w", ErrLobbyNotWaiting)
+	}
+	if settings.QuestionPool == nil {
+		settings.QuestionPool = g.QuestionPool
+	}
+	g.QuestionCount = settings.QuestionCount
+	g.Countdown = settings.Countdown
+	g.QuestionTimeout = settings.QuestionTimeout
+	g.LobbyOptions = settings.LobbyOptions
+	g.SetLastGameInteraction()
+	g.publish(EventSettings, settings.Summary())
+
+	// the ready check may have changed, so the count of who is needed may have too, and it may even be time to start.
+	g.sendRoster()
+	g.autoStartIfReady()
+	return nil
+}
+
+func (g *GameLobby) playerByID(playerID string) *Player {
+	for _, player := range g.Players {
+		if player.ID == playerID {
+			return player
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package game
+
+import (
+	"crypto/subtle"
+	"fmt"
+)
+
+// Visibility says whether a lobby is listed for anyone to find, or only reachable by its link.
+type Visibility string
+
+const (
+	Public  Visibility = "public"
+	Private Visibility = "private"
+)
+
+// LateJoinPolicy is what happens to someone who tries to join once the game has started.
+type LateJoinPolicy string
+
+const (
+	LateJoinReject   LateJoinPolicy = "reject"   // they are turned away, see ErrGameAlreadyStarted
+	LateJoinSpectate LateJoinPolicy = "spectate" // they get in, but only to watch
+)
+
+// JoinRules say who can get into a lobby. the zero value is a public lobby anyone can join while it is waiting.
+// the password is kept apart from these, in LobbyOptions, so that the rules can be shown to players without giving it away.
+type JoinRules struct {
+	MaxPlayers int            `json:"maxPlayers,omitempty"` // 0 means no limit. spectators do not count.
+	Visibility Visibility     `json:"visibility,omitempty"` // public if blank
+	LateJoin   LateJoinPolicy `json:"lateJoin,omitempty"`   // reject if blank
+}
+
+// Validate checks the rules make sense.
+func (r JoinRules) Validate() error {
+	if r.MaxPlayers < 0 {
+		return fmt.Errorf("max players cannot be negative")
+	}
+	switch r.Visibility {
+	case "", Public, Private:
+	default:
+		return fmt.Errorf("unknown visibility %q", r.Visibility)
+	}
+	switch r.LateJoin {
+	case "", LateJoinReject, LateJoinSpectate:
+	default:
+		return fmt.Errorf("unknown late join policy %q", r.LateJoin)
+	}
+	return nil
+}
+
+func (r JoinRules) visibility() Visibility {
+	if r.Visibility == "" {
+		return Public
+	}
+	return r.Visibility
+}
+
+func (r JoinRules) lateJoin() LateJoinPolicy {
+	if r.LateJoin == "" {
+		return LateJoinReject
+	}
+	return r.LateJoin
+}
+
+// Join is how players get into an existing lobby, going by its join rules and password.
+// once the game has started they are either turned away or let in as a spectator, depending on the rules.
+func (g *GameLobby) Join(sessionID, name, password string) (*Player, error) {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	if subtle.ConstantTimeCompare([]byte(password), []byte(g.Password)) != 1 {
+		return nil, ErrWrongPassword
+	}
+	switch {
+	case g.State == Waiting:
+		return g.addPlayer(sessionID, name, false)
+	case g.State == Ended:
+		return nil, fmt.Errorf("cannot join, %w", ErrGameEnded)
+	case g.JoinRules.lateJoin() == LateJoinSpectate:
+		return g.addPlayer(sessionID, name, true)
+	default:
+		return nil, fmt.Errorf("cannot join, %w", ErrGameAlreadyStarted)
+	}
+}
+
+// full is whether there is no room for another player. must be called while holding the lobby mutex.
+func (g *GameLobby) full() bool {
+	return g.JoinRules.MaxPlayers > 0 && len(g.contestants()) >= g.JoinRules.MaxPlayers
+}
+
+// contestants are the players actually playing, which is everyone but the spectators. must be called while holding the lobby mutex.
+func (g *GameLobby) contestants() []*Player {
+	contestants := make([]*Player, 0, len(g.Players))
+	for _, player := range g.Players {
+		if !player.Spectator {
+			contestants = append(contestants, player)
+		}
+	}
+	return contestants
+}
@@ -3,6 +3,7 @@ package game
 import (
 	"github.com/google/uuid"
 	"log"
+	"sort"
 	"sync"
 	"time"
 )
@@ -12,6 +13,7 @@ type Lobbies struct {
 	mutex           sync.Mutex
 	lobbies         map[string]*GameLobby
 	cleanupInterval time.Duration // Cleanup interval in minutes
+	analytics       AnalyticsSink
 }
 
 // NewLobbies creates and returns a new Lobbies instance
@@ -19,9 +21,17 @@ func NewLobbies(cleanupInterval time.Duration) *Lobbies {
 	return &Lobbies{
 		lobbies:         make(map[string]*GameLobby),
 		cleanupInterval: cleanupInterval,
+		analytics:       noAnalytics{},
 	}
 }
 
+// SetAnalyticsSink sets where lobbies created from now on report their analytics events.
+func (l *Lobbies) SetAnalyticsSink(sink AnalyticsSink) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.analytics = sink
+}
+
 // GetLobby attempts to find and return a lobby by its ID.
 // Returns a pointer to the GameLobby and a boolean indicating whether the lobby was found.
 func (l *Lobbies) GetLobby(lobbyId string) (*GameLobby, bool) {
@@ -32,24 +42,116 @@ func (l *Lobbies) GetLobby(lobbyId string) (*GameLobby, bool) {
 	return lobby, found
 }
 
-func (l *Lobbies) AddLobby(questionCount, countdown int, player *Player) string {
+// AddLobby creates a new lobby, optionally with a first player in it, and returns the new lobby id along with that player as they were added.
+func (l *Lobbies) AddLobby(questionCount, countdown, questionTimeout int, options LobbyOptions, player *Player) (string, *Player, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
+	newLobby, added, err := l.addLobby(LobbySettings{QuestionCount: questionCount, Countdown: countdown, QuestionTimeout: questionTimeout, LobbyOptions: options}, player)
+	if err != nil {
+		return "", nil, err
+	}
+	return newLobby.ID, added, nil
+}
+
+// addLobby does the work for AddLobby and QuickMatch, returning the player it was made for as they were added to it, if there is one.
+// must be called while holding the lobbies mutex.
+func (l *Lobbies) addLobby(settings LobbySettings, player *Player) (*GameLobby, *Player, error) {
 	// Generate a unique ID for the new lobby
 	newLobbyID := uuid.New().String()
 
 	// Create a new GameLobby instance
-	newLobby := NewGameLobby(questionCount, countdown)
+	newLobby := NewGameLobby(settings.QuestionCount, settings.Countdown, settings.QuestionTimeout)
+	newLobby.ID = newLobbyID
+	newLobby.LobbyOptions = settings.LobbyOptions
+	if l.analytics != nil {
+		newLobby.analytics = l.analytics
+	}
+	newLobby.record(AnalyticsEvent{Type: AnalyticsLobbyCreated})
 
 	// If a player instance is provided, add the player to the new lobby
+	var added *Player
 	if player != nil {
-		newLobby.AddPlayer(player.SessionID)
+		var err error
+		if added, err = newLobby.AddPlayer(player.SessionID, player.Name); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// Add the new lobby to the lobbies map
 	l.lobbies[newLobbyID] = newLobby
-	return newLobbyID
+	return newLobby, added, nil
+}
+
+// LobbySummary is what the lobby browser shows about a lobby.
+type LobbySummary struct {
+	ID         string        `json:"id"`
+	Host       string        `json:"host"` // display name of the host
+	Players    int           `json:"players"`
+	Spectators int           `json:"spectators"`
+	QuickMatch bool          `json:"quickMatch"` // made by QuickMatch, so it starts by itself once it is full
+	Settings   SettingsEvent `json:"settings"`
+}
+
+// Summary describes the lobby for the lobby browser.
+func (g *GameLobby) Summary() LobbySummary {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.summary()
+}
+
+func (g *GameLobby) summary() LobbySummary {
+	summary := LobbySummary{
+		ID:         g.ID,
+		Players:    len(g.contestants()),
+		Spectators: g.spectators,
+		QuickMatch: g.matchKey != "",
+		Settings:   g.settings().Summary(),
+	}
+	if host := g.hostPlayer(); host != nil {
+		summary.Host = host.Name
+	}
+	return summary
+}
+
+// PublicLobbies lists the public lobbies that are still waiting for players, fullest first so that games fill up and get going.
+func (l *Lobbies) PublicLobbies() []LobbySummary {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	summaries := make([]LobbySummary, 0)
+	for _, lobby := range l.lobbies {
+		lobby.mutex.Lock()
+		if lobby.State == Waiting && lobby.JoinRules.visibility() == Public {
+			summaries = append(summaries, lobby.summary())
+		}
+		lobby.mutex.Unlock()
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Players != summaries[j].Players {
+			return summaries[i].Players > summaries[j].Players
+		}
+		return summaries[i].ID < summaries[j].ID
+	})
+	return summaries
+}
+
+// LobbiesMetrics is a summary of every lobby the server knows about.
+type LobbiesMetrics struct {
+	Lobbies   int              `json:"lobbies"`
+	Broadcast BroadcastMetrics `json:"broadcast"`
+}
+
+// Metrics adds up the event delivery metrics across all lobbies. lobbies that were cleaned up no longer count.
+func (l *Lobbies) Metrics() LobbiesMetrics {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	metrics := LobbiesMetrics{Lobbies: len(l.lobbies)}
+	for _, lobby := range l.lobbies {
+		metrics.Broadcast.add(lobby.BroadcastMetrics())
+	}
+	return metrics
 }
 
 func (l *Lobbies) StartCleanupRoutine() {
@@ -71,13 +173,10 @@ func (l *Lobbies) cleanupExpiredLobbies() {
 		log.Printf("running cleanup routine ... checking on lobby id %s", id)
 		// Determine if the lobby is expired
 		if time.Since(lobby.LastGameInteraction) > l.cleanupInterval {
-			log.Printf("removing old lobby uuid %s, closing message channels of %d gamelobby players", id, len(lobby.Players))
+			log.Printf("removing old lobby uuid %s, closing subscriptions of %d gamelobby players", id, len(lobby.Players))
 			// Perform cleanup for this lobby
-			// This should include closing player channels, removing players, etc.
-			// For example, closing player channels (simplified):
-			for _, player := range lobby.Players {
-				close(player.MessageChannel)
-			}
+			// ending every subscription also closes any websockets still connected to it.
+			lobby.broadcaster.Close()
 			// Remove the lobby from the map
 			delete(l.lobbies, id)
 		}
@@ -1,17 +1,64 @@
 package game
 
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const maxPlayerNameLength = 24
+const maxChatLength = 280
+
 type Player struct {
+	ID                string // Public id that is safe to share with other players, unlike the session id.
 	SessionID         string
+	Name              string
+	Ready             bool
+	Spectator         bool // only watching the game, see LateJoinSpectate
+	host              bool // see GameLobby.hostPlayer
+	connections       int  // how many websockets the player has open, see GameLobby.Connect and GameLobby.Disconnect
 	Score             int
-	QuestionsAnswered []string     //to hold the ids of the questions that the player answered, in case 'no player answers it correctly first', so we have some way to track it.
-	MessageChannel    chan Message // Channel for sending messages to the player
+	CorrectCount      int
+	WrongCount        int
+	totalAnswerTime   time.Duration // summed over every answer, see AverageAnswerTime
+	QuestionsAnswered []string      //to hold the ids of the questions that the player answered, in case 'no player answers it correctly first', so we have some way to track it.
+	subscription      *Subscription // where the player's events are queued until their websocket picks them up
+	attemptQuestionID string        // the question retryAt is about, see startAttempt
+	wrongAnswers      int           // wrong answers this game, see AnswerRules.LockoutAfterWrong
+	retryAt           time.Time     // when the player may answer attemptQuestionID again, under RetryAfterCooldown
+}
+
+// PlayerSummary is what other players get to see about a player.
+type PlayerSummary struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Ready     bool   `json:"ready"`
+	Host      bool   `json:"host"`
+	Spectator bool   `json:"spectator"`
+}
+
+// Messages is the player's current queue of events from the lobby, see GameLobby.Connect.
+func (p *Player) Messages() <-chan *Event {
+	return p.subscription.Events()
 }
 
-// Message struct to encapsulate game messages
-type Message interface{}
+func (p *Player) Summary() PlayerSummary {
+	return PlayerSummary{ID: p.ID, Name: p.Name, Ready: p.Ready, Host: p.host, Spectator: p.Spectator}
+}
 
-func (p *Player) SendMessage(message Message) {
-	p.MessageChannel <- message
+// resetForRematch clears everything about the player that only applies to one round.
+func (p *Player) resetForRematch() {
+	p.Ready = false
+	p.Score = 0
+	p.CorrectCount = 0
+	p.WrongCount = 0
+	p.totalAnswerTime = 0
+	p.QuestionsAnswered = []string{}
+	p.attemptQuestionID = ""
+	p.wrongAnswers = 0
+	p.retryAt = time.Time{}
 }
 
 func (p *Player) HasAnsweredQuestion(questionID string) bool {
@@ -22,3 +69,18 @@ func (p *Player) HasAnsweredQuestion(questionID string) bool {
 	}
 	return false
 }
+
+// ValidatePlayerName tidies up a requested display name and checks that it is something we are willing to show to other players.
+// a blank name is allowed, the lobby will make one up.
+func ValidatePlayerName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ") // trim and collapse runs of whitespace
+	if utf8.RuneCountInString(name) > maxPlayerNameLength {
+		return "", fmt.Errorf("%w, it is too long", ErrInvalidPlayerName)
+	}
+	for _, r := range name {
+		if !unicode.IsPrint(r) {
+			return "", fmt.Errorf("%w, it contains invalid characters", ErrInvalidPlayerName)
+		}
+	}
+	return name, nil
+}
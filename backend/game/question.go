@@ -1,8 +1,45 @@
 package game
 
+// Question is any of the question types, see QuestionType. the fields after the options only apply to some of them.
 type Question struct {
-	ID           string   `json:"id"`
-	QuestionText string   `json:"questionText"`
-	Options      []string `json:"options"`
-	CorrectIndex int      `json:"correctIndex"`
+	ID             string         `json:"id"`
+	QuestionText   string         `json:"questionText"`
+	Type           QuestionType   `json:"type,omitempty"` // SingleChoice if blank
+	Options        []string       `json:"options,omitempty"`
+	CorrectIndex   int            `json:"correctIndex"`             // for single choice and true/false questions
+	CorrectIndexes []int          `json:"correctIndexes,omitempty"` // for multi-select questions, every option that is right
+	Credit         CreditRule     `json:"credit,omitempty"`         // for multi-select questions, CreditAll if blank
+	Numeric        *NumericAnswer `json:"numeric,omitempty"`        // for numeric questions
+	Category       string         `json:"category,omitempty"`
+	Tags           []string       `json:"tags,omitempty"`
+	Difficulty     int            `json:"difficulty,omitempty"` // MinDifficulty to MaxDifficulty, 0 if it hasn't been rated
+}
+
+// PublicQuestion is a question as the players get to see it, without the answer.
+type PublicQuestion struct {
+	ID           string       `json:"id"`
+	QuestionText string       `json:"questionText"`
+	Type         QuestionType `json:"type"`
+	Options      []string     `json:"options"`
+	Unit         string       `json:"unit,omitempty"` // what a numeric question's answer is measured in
+	Category     string       `json:"category,omitempty"`
+	Difficulty   int          `json:"difficulty,omitempty"`
+}
+
+func (q *Question) Public() PublicQuestion {
+	public := PublicQuestion{
+		ID:           q.ID,
+		QuestionText: q.QuestionText,
+		Type:         q.questionType(),
+		Options:      q.Options,
+		Category:     q.Category,
+		Difficulty:   q.Difficulty,
+	}
+	if public.Options == nil {
+		public.Options = []string{}
+	}
+	if q.Numeric != nil {
+		public.Unit = q.Numeric.Unit
+	}
+	return public
 }
@@ -0,0 +1,167 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	MinDifficulty = 1 // easiest
+	MaxDifficulty = 5 // hardest
+)
+
+// QuestionFilter narrows down which questions from the pool a lobby will play. the zero value lets everything through.
+type QuestionFilter struct {
+	Categories    []string    `json:"categories,omitempty"` // any of these, matched case-insensitively
+	Tags          []string    `json:"tags,omitempty"`       // questions with at least one of these
+	MinDifficulty int         `json:"minDifficulty,omitempty"`
+	MaxDifficulty int         `json:"maxDifficulty,omitempty"`
+	DifficultyMix map[int]int `json:"difficultyMix,omitempty"` // how many questions of each difficulty, adding up to the question count
+}
+
+// IsZero is true when the filter doesn't narrow anything down.
+func (f QuestionFilter) IsZero() bool {
+	return len(f.Categories) == 0 && len(f.Tags) == 0 && f.MinDifficulty == 0 && f.MaxDifficulty == 0 && len(f.DifficultyMix) == 0
+}
+
+// Validate checks the filter makes sense on its own and for the given question count, 0 meaning the whole pool.
+func (f QuestionFilter) Validate(questionCount int) error {
+	if questionCount < 0 {
+		return errors.New("question count cannot be negative")
+	}
+	for _, difficulty := range []int{f.MinDifficulty, f.MaxDifficulty} {
+		if difficulty != 0 && (difficulty < MinDifficulty || difficulty > MaxDifficulty) {
+			return fmt.Errorf("difficulty must be between %d and %d", MinDifficulty, MaxDifficulty)
+		}
+	}
+	if f.MinDifficulty != 0 && f.MaxDifficulty != 0 && f.MinDifficulty > f.MaxDifficulty {
+		return errors.New("minimum difficulty is above the maximum difficulty")
+	}
+	if len(f.DifficultyMix) == 0 {
+		return nil
+	}
+	total := 0
+	for difficulty, count := range f.DifficultyMix {
+		if difficulty < MinDifficulty || difficulty > MaxDifficulty {
+			return fmt.Errorf("difficulty mix has difficulty %d, difficulty must be between %d and %d", difficulty, MinDifficulty, MaxDifficulty)
+		}
+		if !f.difficultyInRange(difficulty) {
+			return fmt.Errorf("difficulty mix asks for difficulty %d which is outside the difficulty range", difficulty)
+		}
+		if count < 0 {
+			return errors.New("difficulty mix counts cannot be negative")
+		}
+		total += count
+	}
+	if questionCount != 0 && total != questionCount {
+		return fmt.Errorf("difficulty mix adds up to %d questions but the question count is %d", total, questionCount)
+	}
+	return nil
+}
+
+func (f QuestionFilter) difficultyInRange(difficulty int) bool {
+	return (f.MinDifficulty == 0 || difficulty >= f.MinDifficulty) && (f.MaxDifficulty == 0 || difficulty <= f.MaxDifficulty)
+}
+
+// Matches says whether the question gets through the categories, tags and difficulty range.
+// once a difficulty range is given, questions without a difficulty are left out.
+func (f QuestionFilter) Matches(q *Question) bool {
+	if len(f.Categories) > 0 && !containsFold(f.Categories, q.Category) {
+		return false
+	}
+	if len(f.Tags) > 0 {
+		tagged := false
+		for _, tag := range q.Tags {
+			tagged = tagged || containsFold(f.Tags, tag)
+		}
+		if !tagged {
+			return false
+		}
+	}
+	if f.MinDifficulty != 0 || f.MaxDifficulty != 0 {
+		return q.Difficulty != 0 && f.difficultyInRange(q.Difficulty)
+	}
+	return true
+}
+
+// Select picks questionCount shuffled questions from the pool that match the filter, 0 meaning every match.
+// with no filter a count bigger than the pool just plays the whole pool, as it always has,
+// but once a filter is in play not being able to fill the count is an ErrNotEnoughQuestions.
+func (f QuestionFilter) Select(pool []*Question, questionCount int) ([]*Question, error) {
+	if err := f.Validate(questionCount); err != nil {
+		return nil, err
+	}
+
+	var matching []*Question
+	for _, q := range pool {
+		if f.Matches(q) {
+			matching = append(matching, q)
+		}
+	}
+	rand.Shuffle(len(matching), func(i, j int) {
+		matching[i], matching[j] = matching[j], matching[i]
+	})
+
+	if len(f.DifficultyMix) > 0 {
+		return f.selectMix(matching)
+	}
+	if f.IsZero() {
+		if questionCount == 0 || questionCount > len(matching) {
+			return matching, nil
+		}
+		return matching[:questionCount], nil
+	}
+	if len(matching) == 0 {
+		return nil, fmt.Errorf("%w: no questions match the filter", ErrNotEnoughQuestions)
+	}
+	if questionCount == 0 {
+		return matching, nil
+	}
+	if questionCount > len(matching) {
+		return nil, fmt.Errorf("%w: %d questions wanted but only %d match the filter", ErrNotEnoughQuestions, questionCount, len(matching))
+	}
+	return matching[:questionCount], nil
+}
+
+// selectMix takes the asked for number of each difficulty from the already shuffled matches, then mixes them together.
+func (f QuestionFilter) selectMix(matching []*Question) ([]*Question, error) {
+	byDifficulty := make(map[int][]*Question)
+	for _, q := range matching {
+		byDifficulty[q.Difficulty] = append(byDifficulty[q.Difficulty], q)
+	}
+
+	// go through the difficulties in order so the error is the same every time.
+	difficulties := make([]int, 0, len(f.DifficultyMix))
+	for difficulty := range f.DifficultyMix {
+		difficulties = append(difficulties, difficulty)
+	}
+	sort.Ints(difficulties)
+
+	var selected []*Question
+	for _, difficulty := range difficulties {
+		wanted, available := f.DifficultyMix[difficulty], byDifficulty[difficulty]
+		if wanted > len(available) {
+			return nil, fmt.Errorf("%w: %d difficulty %d questions wanted but only %d match the filter", ErrNotEnoughQuestions, wanted, difficulty, len(available))
+		}
+		selected = append(selected, available[:wanted]...)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("%w: the difficulty mix asks for no questions", ErrNotEnoughQuestions)
+	}
+	rand.Shuffle(len(selected), func(i, j int) {
+		selected[i], selected[j] = selected[j], selected[i]
+	})
+	return selected, nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,419 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QuestionType says how a question is answered and marked.
+type QuestionType string
+
+const (
+	SingleChoice QuestionType = "single"      // pick the one correct option, the original kind of question
+	TrueFalse    QuestionType = "trueFalse"   // a single choice between true and false, which are the options unless it says otherwise
+	MultiSelect  QuestionType = "multiSelect" // pick every correct option, marked by the question's CreditRule
+	Numeric      QuestionType = "numeric"     // guess a number, marked by the question's NumericAnswer
+)
+
+// CreditRule is how a multi-select answer is marked.
+type CreditRule string
+
+const (
+	CreditAll     CreditRule = "all"     // every correct option and nothing else gets the points, anything less gets nothing
+	CreditPartial CreditRule = "partial" // a share of the points for each correct option picked, less a share for each wrong one
+)
+
+// NumericRule is how a numeric answer is marked.
+type NumericRule string
+
+const (
+	// ClosestWithoutOver gives the points to whoever guessed nearest the value without going over it.
+	// that can only be worked out once everyone has guessed, so these questions are always locked in and revealed, as in RoundEveryone.
+	ClosestWithoutOver NumericRule = "closestWithoutOver"
+	// WithinTolerance gives points to any guess inside one of the question's tolerance bands.
+	WithinTolerance NumericRule = "tolerance"
+)
+
+// ToleranceBand is how far off a guess can be and still get a share of the points.
+type ToleranceBand struct {
+	Within float64 `json:"within"` // how far from the value, either way
+	Credit float64 `json:"credit"` // share of the points, more than 0 and up to 1
+}
+
+// NumericAnswer is the answer to a numeric question and how close guesses have to be.
+type NumericAnswer struct {
+	Value float64         `json:"value"`
+	Rule  NumericRule     `json:"rule,omitempty"`  // WithinTolerance if blank
+	Bands []ToleranceBand `json:"bands,omitempty"` // with WithinTolerance, only an exact guess scores if there are none
+	Unit  string          `json:"unit,omitempty"`  // shown to players, like "USD"
+}
+
+func (n *NumericAnswer) rule() NumericRule {
+	if n.Rule == "" {
+		return WithinTolerance
+	}
+	return n.Rule
+}
+
+func (q *Question) questionType() QuestionType {
+	if q.Type == "" {
+		return SingleChoice
+	}
+	return q.Type
+}
+
+func (q *Question) credit() CreditRule {
+	if q.Credit == "" {
+		return CreditAll
+	}
+	return q.Credit
+}
+
+// lockedIn is whether the question is always played by everyone locking in an answer, whatever the lobby's round mode.
+func (q *Question) lockedIn() bool {
+	return q.questionType() == Numeric && q.Numeric != nil && q.Numeric.rule() == ClosestWithoutOver
+}
+
+// Normalize fills in what a question can leave out, which is the options of a true/false question. the question sources call it.
+func (q *Question) Normalize() {
+	if q.Type == TrueFalse && len(q.Options) == 0 {
+		q.Options = []string{"True", "False"}
+	}
+}
+
+// Check lists everything wrong with how the question is put together for its type, nothing if it is fine.
+func (q *Question) Check() []string {
+	var problems []string
+	if strings.TrimSpace(q.QuestionText) == "" {
+		problems = append(problems, "question text is blank")
+	}
+	switch q.questionType() {
+	case SingleChoice:
+		problems = append(problems, q.checkOptions()...)
+		problems = append(problems, q.checkCorrectIndex()...)
+	case TrueFalse:
+		if len(q.Options) != 2 {
+			problems = append(problems, fmt.Sprintf("true/false questions have 2 options, this has %d", len(q.Options)))
+		}
+		problems = append(problems, q.checkCorrectIndex()...)
+	case MultiSelect:
+		problems = append(problems, q.checkOptions()...)
+		if len(q.CorrectIndexes) == 0 {
+			problems = append(problems, "multi-select questions need correctIndexes")
+		}
+		seen := make(map[int]bool, len(q.CorrectIndexes))
+		for _, index := range q.CorrectIndexes {
+			if index < 0 || index >= len(q.Options) {
+				problems = append(problems, fmt.Sprintf("correctIndexes has %d, which is out of range for %d options", index, len(q.Options)))
+			} else if seen[index] {
+				problems = append(problems, fmt.Sprintf("correctIndexes has %d more than once", index))
+			}
+			seen[index] = true
+		}
+		if rule := q.credit(); rule != CreditAll && rule != CreditPartial {
+			problems = append(problems, fmt.Sprintf("unknown credit rule %q, expected %q or %q", q.Credit, CreditAll, CreditPartial))
+		}
+	case Numeric:
+		if len(q.Options) > 0 {
+			problems = append(problems, "numeric questions have no options")
+		}
+		if q.Numeric == nil {
+			problems = append(problems, "numeric questions need a numeric answer")
+			break
+		}
+		switch q.Numeric.rule() {
+		case WithinTolerance:
+			for _, band := range q.Numeric.Bands {
+				if band.Within < 0 || band.Credit <= 0 || band.Credit > 1 {
+					problems = append(problems, fmt.Sprintf("tolerance band %+v needs a distance of 0 or more and a credit over 0 and up to 1", band))
+				}
+			}
+		case ClosestWithoutOver:
+			if len(q.Numeric.Bands) > 0 {
+				problems = append(problems, fmt.Sprintf("tolerance bands only apply to the %q rule", WithinTolerance))
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("unknown numeric rule %q, expected %q or %q", q.Numeric.Rule, WithinTolerance, ClosestWithoutOver))
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("unknown question type %q", q.Type))
+	}
+	return problems
+}
+
+// checkOptions checks the options of a question players pick from.
+func (q *Question) checkOptions() []string {
+	var problems []string
+	if len(q.Options) < 2 {
+		problems = append(problems, fmt.Sprintf("has %d options, needs at least 2", len(q.Options)))
+	}
+	firstWithOption := make(map[string]int, len(q.Options))
+	for i, option := range q.Options {
+		normalized := strings.Join(strings.Fields(strings.ToLower(option)), " ")
+		if normalized == "" {
+			problems = append(problems, fmt.Sprintf("option %d is blank", i))
+			continue
+		}
+		if first, ok := firstWithOption[normalized]; ok {
+			problems = append(problems, fmt.Sprintf("option %d %q repeats option %d", i, option, first))
+			continue
+		}
+		firstWithOption[normalized] = i
+	}
+	return problems
+}
+
+func (q *Question) checkCorrectIndex() []string {
+	if q.CorrectIndex < 0 || q.CorrectIndex >= len(q.Options) {
+		return []string{fmt.Sprintf("correctIndex %d is out of range for %d options", q.CorrectIndex, len(q.Options))}
+	}
+	return nil
+}
+
+// Answer is what a player submits. which part counts depends on the question's type: Choice for single choice and true/false,
+// Choices for multi-select and Value for numeric questions. Bool is for true/false questions, and is worked out to the option
+// that says true or false when the answer is checked, see checkAnswer.
+// over json it is a number (an option index, or the guess at a numeric question), true or false, or an array of option indexes.
+type Answer struct {
+	Choice  *int
+	Choices []int
+	Value   *float64
+	Bool    *bool
+}
+
+// ChoiceAnswer picks one option, for single choice and true/false questions.
+func ChoiceAnswer(index int) Answer {
+	return Answer{Choice: &index}
+}
+
+// ChoicesAnswer picks options for a multi-select question.
+func ChoicesAnswer(indexes ...int) Answer {
+	return Answer{Choices: indexes}
+}
+
+// BoolAnswer is true or false, for true/false questions whose options say so.
+func BoolAnswer(answer bool) Answer {
+	return Answer{Bool: &answer}
+}
+
+// ValueAnswer is a guess at a numeric question.
+func ValueAnswer(value float64) Answer {
+	return Answer{Value: &value}
+}
+
+func (a *Answer) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	switch {
+	case trimmed == "null":
+		*a = Answer{}
+		return nil
+	case strings.HasPrefix(trimmed, "["):
+		var choices []int
+		if err := json.Unmarshal(data, &choices); err != nil {
+			return fmt.Errorf("an answer array has to be option indexes: %w", err)
+		}
+		*a = ChoicesAnswer(choices...)
+		return nil
+	case trimmed == "true" || trimmed == "false":
+		*a = BoolAnswer(trimmed == "true")
+		return nil
+	}
+	value, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil {
+		return fmt.Errorf("an answer has to be a number, true or false, or an array of option indexes")
+	}
+	*a = ValueAnswer(value)
+	if value == math.Trunc(value) && math.Abs(value) <= math.MaxInt32 {
+		index := int(value)
+		a.Choice = &index
+	}
+	return nil
+}
+
+// checkAnswer makes sure the answer is the right shape for the question, returning it with true or false worked out to an option.
+// a single choice outside the options is only refused when strict, otherwise it is just wrong, as it always has been when racing.
+func (q *Question) checkAnswer(answer Answer, strict bool) (Answer, error) {
+	if answer.Bool != nil {
+		// the options could be in either order, or not say true and false at all, so go by what they say.
+		index := -1
+		for i, option := range q.Options {
+			if q.questionType() == TrueFalse && strings.EqualFold(strings.TrimSpace(option), strconv.FormatBool(*answer.Bool)) {
+				index = i
+			}
+		}
+		if index < 0 {
+			return answer, fmt.Errorf("%w, this question's options are not true and false, answer with the index of one", ErrInvalidAnswer)
+		}
+		answer = ChoiceAnswer(index)
+	}
+	switch q.questionType() {
+	case SingleChoice, TrueFalse:
+		if answer.Choice == nil {
+			return answer, fmt.Errorf("%w, this question is answered with the index of one option", ErrInvalidAnswer)
+		}
+		if strict && (*answer.Choice < 0 || *answer.Choice >= len(q.Options)) {
+			return answer, ErrInvalidAnswer
+		}
+	case MultiSelect:
+		choices := answer.Choices
+		if choices == nil && answer.Choice != nil {
+			choices = []int{*answer.Choice}
+		}
+		if len(choices) == 0 {
+			return answer, fmt.Errorf("%w, this question is answered with an array of option indexes", ErrInvalidAnswer)
+		}
+		seen := make(map[int]bool, len(choices))
+		for _, choice := range choices {
+			if choice < 0 || choice >= len(q.Options) || seen[choice] {
+				return answer, fmt.Errorf("%w, options can only be picked once and have to be in range", ErrInvalidAnswer)
+			}
+			seen[choice] = true
+		}
+	case Numeric:
+		if answer.Value == nil {
+			return answer, fmt.Errorf("%w, this question is answered with a number", ErrInvalidAnswer)
+		}
+	}
+	return answer, nil
+}
+
+// grade is how an answer was marked.
+type grade struct {
+	credit float64 // share of the points earned, 1 for a fully correct answer and 0 for a wrong one
+	choice int     // the one option picked, for analytics, -1 when the answer is not a single option
+	detail string  // how partial credit was worked out
+}
+
+func (gr grade) correct() bool {
+	return gr.credit >= 1
+}
+
+// grade marks an answer that checkAnswer has let through.
+// closest without going over depends on everyone's guesses, see gradeClosest, so on its own a guess at one of those only scores if it is exact.
+func (q *Question) grade(answer Answer) grade {
+	switch q.questionType() {
+	case MultiSelect:
+		choices := answer.Choices
+		if choices == nil {
+			choices = []int{*answer.Choice}
+		}
+		right, wrong := 0, 0
+		for _, choice := range choices {
+			if q.isCorrectIndex(choice) {
+				right++
+			} else {
+				wrong++
+			}
+		}
+		result := grade{choice: -1}
+		if right == len(q.CorrectIndexes) && wrong == 0 {
+			result.credit = 1
+		} else if q.credit() == CreditPartial && right > wrong {
+			result.credit = float64(right-wrong) / float64(len(q.CorrectIndexes))
+			result.detail = fmt.Sprintf("%d of %d correct options with %d wrong", right, len(q.CorrectIndexes), wrong)
+		}
+		return result
+	case Numeric:
+		result := grade{choice: -1}
+		distance := math.Abs(*answer.Value - q.Numeric.Value)
+		if distance == 0 {
+			result.credit = 1
+			return result
+		}
+		if q.Numeric.rule() == WithinTolerance {
+			// the best band the guess is in counts.
+			for _, band := range q.Numeric.Bands {
+				if distance <= band.Within && band.Credit > result.credit {
+					result.credit = band.Credit
+					result.detail = fmt.Sprintf("within %s of the answer", formatNumber(band.Within))
+				}
+			}
+		}
+		return result
+	default:
+		result := grade{choice: *answer.Choice}
+		if *answer.Choice == q.CorrectIndex {
+			result.credit = 1
+		}
+		return result
+	}
+}
+
+// gradeClosest marks guesses at a closest without going over question against each other:
+// the nearest guesses that are not over the value are right, and everything else is wrong.
+func (q *Question) gradeClosest(answers []Answer) []grade {
+	best, found := 0.0, false
+	for _, answer := range answers {
+		if *answer.Value <= q.Numeric.Value && (!found || *answer.Value > best) {
+			best, found = *answer.Value, true
+		}
+	}
+	grades := make([]grade, len(answers))
+	for i, answer := range answers {
+		grades[i] = grade{choice: -1}
+		if found && *answer.Value == best {
+			grades[i].credit = 1
+		}
+	}
+	return grades
+}
+
+func (q *Question) isCorrectIndex(index int) bool {
+	for _, correct := range q.CorrectIndexes {
+		if correct == index {
+			return true
+		}
+	}
+	return false
+}
+
+// Solution is the answer to a question, as revealed to players once it is over.
+// CorrectIndex is the answer to single choice and true/false questions, the first correct option of a multi-select one
+// (so clients that only know about one correct option still show something) and -1 for numeric questions.
+type Solution struct {
+	CorrectIndex   int      `json:"correctIndex"`
+	CorrectIndexes []int    `json:"correctIndexes,omitempty"` // every correct option of a multi-select question
+	CorrectValue   *float64 `json:"correctValue,omitempty"`   // the answer to a numeric question
+}
+
+func (q *Question) solution() Solution {
+	switch q.questionType() {
+	case MultiSelect:
+		indexes := append([]int(nil), q.CorrectIndexes...)
+		sort.Ints(indexes)
+		solution := Solution{CorrectIndex: -1, CorrectIndexes: indexes}
+		if len(indexes) > 0 {
+			solution.CorrectIndex = indexes[0]
+		}
+		return solution
+	case Numeric:
+		value := q.Numeric.Value
+		return Solution{CorrectIndex: -1, CorrectValue: &value}
+	default:
+		return Solution{CorrectIndex: q.CorrectIndex}
+	}
+}
+
+// answerText is the answer to a question written out.
+func (q *Question) answerText() string {
+	switch q.questionType() {
+	case MultiSelect:
+		texts := make([]string, 0, len(q.CorrectIndexes))
+		for _, index := range q.solution().CorrectIndexes {
+			texts = append(texts, q.Options[index])
+		}
+		return strings.Join(texts, ", ")
+	case Numeric:
+		return strings.TrimSpace(formatNumber(q.Numeric.Value) + " " + q.Numeric.Unit)
+	default:
+		return q.Options[q.CorrectIndex]
+	}
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// QuickMatch puts a player into an open lobby that was made for players wanting the same settings and lobby size,
+// or makes a new one for them if there isn't one. the game starts by itself as soon as targetSize players are in.
+// quick match lobbies are always public, with no password, no ready check and room for exactly targetSize players.
+func (l *Lobbies) QuickMatch(settings LobbySettings, targetSize int, sessionID, name string) (*GameLobby, *Player, error) {
+	if targetSize < 1 {
+		return nil, nil, fmt.Errorf("target size must be at least 1")
+	}
+	settings.JoinRules = JoinRules{MaxPlayers: targetSize, Visibility: Public, LateJoin: LateJoinReject}
+	settings.Password = ""
+	settings.ReadyCheck = ReadyCheck{}
+	key, err := json.Marshal(settings.Summary())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the lobbies stay locked the whole time, so that players arriving together end up in the same lobby rather than making one each.
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var lobby *GameLobby
+	var player *Player
+	for _, candidate := range l.lobbies {
+		if !candidate.openForQuickMatch(string(key)) {
+			continue
+		}
+		if player, err = candidate.Join(sessionID, name, ""); err == nil {
+			lobby = candidate
+			break
+		}
+	}
+	if lobby == nil {
+		lobby, player, err = l.addLobby(settings, &Player{SessionID: sessionID, Name: name})
+		if err != nil {
+			return nil, nil, err
+		}
+		lobby.mutex.Lock()
+		lobby.matchKey = string(key)
+		lobby.targetSize = targetSize
+		lobby.mutex.Unlock()
+	}
+	lobby.startIfFull()
+	return lobby, player, nil
+}
+
+func (g *GameLobby) openForQuickMatch(key string) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.matchKey == key && g.State == Waiting && !g.full()
+}
+
+// startIfFull starts a quick match game once it has all the players it was waiting for.
+func (g *GameLobby) startIfFull() {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	if g.State != Waiting || g.targetSize == 0 || len(g.contestants()) < g.targetSize || g.QuestionPool == nil {
+		return
+	}
+	if err := g.startGame(g.QuestionPool()); err != nil {
+		log.Printf("quick match lobby %s is full but could not start: %v", g.ID, err)
+	}
+}
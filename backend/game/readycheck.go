@@ -0,0 +1,94 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ReadyPolicy is how many players need to say they are ready before a lobby's game can start.
+type ReadyPolicy string
+
+const (
+	ReadyNotRequired ReadyPolicy = "none" // the original behaviour, anyone can start the game at any time
+	ReadyAll         ReadyPolicy = "all"
+	ReadyQuorum      ReadyPolicy = "quorum" // at least ReadyQuorum players
+)
+
+// ReadyCheck is a lobby's setting for holding the game back until enough players are ready.
+type ReadyCheck struct {
+	ReadyPolicy ReadyPolicy `json:"readyPolicy,omitempty"` // blank means ReadyNotRequired
+	ReadyQuorum int         `json:"readyQuorum,omitempty"` // with ReadyQuorum, how many players need to be ready
+	AutoStart   bool        `json:"autoStart,omitempty"`   // start the game by itself as soon as the check passes
+}
+
+func (r ReadyCheck) policy() ReadyPolicy {
+	if r.ReadyPolicy == "" {
+		return ReadyNotRequired
+	}
+	return r.ReadyPolicy
+}
+
+// Validate checks the ready check makes sense, including that a lobby with the given join rules has room for a quorum.
+func (r ReadyCheck) Validate(joinRules JoinRules) error {
+	switch r.policy() {
+	case ReadyNotRequired, ReadyAll:
+		if r.ReadyQuorum != 0 {
+			return fmt.Errorf("ready quorum only applies to the %q ready policy", ReadyQuorum)
+		}
+	case ReadyQuorum:
+		if r.ReadyQuorum < 1 {
+			return errors.New("ready quorum must be at least 1")
+		}
+		if joinRules.MaxPlayers > 0 && r.ReadyQuorum > joinRules.MaxPlayers {
+			return fmt.Errorf("ready quorum of %d can never be reached in a lobby of at most %d players", r.ReadyQuorum, joinRules.MaxPlayers)
+		}
+	default:
+		return fmt.Errorf("unknown ready policy %q, expected %q, %q or %q", r.ReadyPolicy, ReadyNotRequired, ReadyAll, ReadyQuorum)
+	}
+	if r.AutoStart && r.policy() == ReadyNotRequired {
+		return errors.New("auto start needs a ready policy to know when to start")
+	}
+	return nil
+}
+
+// readyCount is how many players are ready and how many need to be for the game to start, 0 needed meaning there is no ready check.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) readyCount() (ready, needed int) {
+	contestants := g.contestants()
+	for _, player := range contestants {
+		if player.Ready {
+			ready++
+		}
+	}
+	switch g.ReadyCheck.policy() {
+	case ReadyAll:
+		needed = len(contestants)
+		if needed == 0 {
+			needed = 1 // an empty lobby is never ready
+		}
+	case ReadyQuorum:
+		needed = g.ReadyCheck.ReadyQuorum
+	}
+	return ready, needed
+}
+
+// checkReady is nil once enough players are ready for the game to start. must be called while holding the lobby mutex.
+func (g *GameLobby) checkReady() error {
+	ready, needed := g.readyCount()
+	if ready < needed {
+		return fmt.Errorf("%w, %d of %d needed are ready", ErrPlayersNotReady, ready, needed)
+	}
+	return nil
+}
+
+// autoStartIfReady starts the game once the ready check passes, in lobbies that asked for that.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) autoStartIfReady() {
+	if g.State != Waiting || !g.ReadyCheck.AutoStart || g.QuestionPool == nil || g.checkReady() != nil {
+		return
+	}
+	if err := g.startGame(g.QuestionPool()); err != nil {
+		log.Printf("lobby %s is ready but could not start: %v", g.ID, err)
+	}
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+)
+
+// a lobby can play any number of games in a row with the same players and settings, each one a round of the series.
+// every round that finishes goes into the lobby's history, which is where the running totals come from
+// and how later rounds avoid asking questions that were already asked.
+
+// RoundResult is how one finished game in the lobby went.
+type RoundResult struct {
+	Round        int               `json:"round"` // 1 for the lobby's first game
+	QuestionIDs  []string          `json:"questionIds"`
+	WinningScore int               `json:"winningScore"`
+	Winners      []PlayerSummary   `json:"winners"`
+	Scoreboard   []ScoreboardEntry `json:"scoreboard"`
+}
+
+// SeriesStanding is a player's running total over every round they played in the lobby, including players who have since left.
+type SeriesStanding struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Total  int    `json:"total"`
+	Wins   int    `json:"wins"`
+	Rounds int    `json:"rounds"` // how many rounds they played in
+}
+
+// RematchEvent is sent when the host takes an ended lobby back to waiting for another round.
+type RematchEvent struct {
+	Round     int              `json:"round"` // the round that is coming up
+	Standings []SeriesStanding `json:"standings"`
+}
+
+// History returns the rounds played in the lobby so far, oldest first, and the running totals over all of them.
+func (g *GameLobby) History() ([]RoundResult, []SeriesStanding) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	history := make([]RoundResult, len(g.history))
+	copy(history, g.history)
+	return history, g.standings()
+}
+
+// recordRound puts the game that just ended into the lobby's history. must be called while holding the lobby mutex.
+func (g *GameLobby) recordRound(status GameStatusResult) {
+	questionIDs := make([]string, 0, len(g.Questions))
+	for _, question := range g.Questions {
+		questionIDs = append(questionIDs, question.ID)
+	}
+	g.history = append(g.history, RoundResult{
+		Round:        len(g.history) + 1,
+		QuestionIDs:  questionIDs,
+		WinningScore: status.WinningScore,
+		Winners:      status.Winners,
+		Scoreboard:   status.Scoreboard,
+	})
+}
+
+// standings adds up the history, best total first. must be called while holding the lobby mutex.
+func (g *GameLobby) standings() []SeriesStanding {
+	byID := make(map[string]*SeriesStanding)
+	standings := make([]*SeriesStanding, 0)
+	for _, round := range g.history {
+		for _, entry := range round.Scoreboard {
+			standing, ok := byID[entry.ID]
+			if !ok {
+				standing = &SeriesStanding{ID: entry.ID}
+				byID[entry.ID] = standing
+				standings = append(standings, standing)
+			}
+			standing.Name = entry.Name // whatever they were called most recently
+			standing.Total += entry.Score
+			standing.Rounds++
+		}
+		for _, winner := range round.Winners {
+			byID[winner.ID].Wins++
+		}
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Total != standings[j].Total {
+			return standings[i].Total > standings[j].Total
+		}
+		return standings[i].Wins > standings[j].Wins
+	})
+	result := make([]SeriesStanding, 0, len(standings))
+	for _, standing := range standings {
+		result = append(result, *standing)
+	}
+	return result
+}
+
+// unusedQuestions is the pool without any question that an earlier round in the lobby already asked.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) unusedQuestions(pool []*Question) []*Question {
+	if len(g.history) == 0 {
+		return pool
+	}
+	used := make(map[string]bool)
+	for _, round := range g.history {
+		for _, id := range round.QuestionIDs {
+			used[id] = true
+		}
+	}
+	unused := make([]*Question, 0, len(pool))
+	for _, question := range pool {
+		if !used[question.ID] {
+			unused = append(unused, question)
+		}
+	}
+	return unused
+}
+
+// Rematch lets the host take an ended lobby back to waiting, with the same players and settings, for another round.
+// everyone's score for the round starts again from nothing, the running totals carry on in the standings.
+// spectators who came in late get to play this time, if there is room for them.
+func (g *GameLobby) Rematch(hostSessionID string) error {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	if _, err := g.authorizeHost(hostSessionID); err != nil {
+		return fmt.Errorf("cannot rematch, %w", err)
+	}
+	if g.State != Ended {
+		return fmt.Errorf("cannot rematch, %w", ErrGameNotEnded)
+	}
+
+	for _, player := range g.Players {
+		player.resetForRematch()
+		if player.Spectator && !g.full() {
+			player.Spectator = false
+			g.broadcaster.SetSpectator(player.subscription, false)
+		}
+	}
+	g.State = Waiting
+	g.CurrentQuestionIndex = 0
+	g.Questions = nil
+	g.SetLastGameInteraction()
+	g.publish(EventRematch, RematchEvent{Round: len(g.history) + 1, Standings: g.standings()})
+	g.sendRoster()
+	g.sendScores(nil)
+	return nil
+}
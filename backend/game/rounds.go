@@ -0,0 +1,151 @@
+package game
+
+import (
+	"fmt"
+	"time"
+)
+
+// RoundMode is how a lobby runs each question.
+type RoundMode string
+
+const (
+	// RoundRace is the original mode, the first correct answer takes the question and the game moves straight on.
+	RoundRace RoundMode = "race"
+	// RoundEveryone has every player lock in one answer. the question closes once they all have, or the timer runs out,
+	// and only then are the answers revealed and scored.
+	RoundEveryone RoundMode = "everyone"
+)
+
+// ParseRoundMode checks a round mode from a request, with a blank one meaning RoundRace.
+func ParseRoundMode(mode string) (RoundMode, error) {
+	switch RoundMode(mode) {
+	case "", RoundRace:
+		return RoundRace, nil
+	case RoundEveryone:
+		return RoundEveryone, nil
+	}
+	return "", fmt.Errorf("unknown round mode %q, expected %q or %q", mode, RoundRace, RoundEveryone)
+}
+
+// lockedAnswer is an answer waiting for the question to close in RoundEveryone.
+type lockedAnswer struct {
+	player  *Player
+	answer  Answer
+	latency time.Duration
+}
+
+// everyoneAnswers is whether the current question is played by everyone locking in an answer, either because of the lobby's
+// round mode or because the question can only be marked once everyone has guessed. must be called while holding the lobby mutex.
+func (g *GameLobby) everyoneAnswers() bool {
+	if g.roundMode() == RoundEveryone {
+		return true
+	}
+	return g.State == Started && g.Questions[g.CurrentQuestionIndex].lockedIn()
+}
+
+// lockInAnswer holds on to a player's answer until the question closes. must be called while holding the lobby mutex.
+func (g *GameLobby) lockInAnswer(player *Player, question *Question, answer Answer) (error, AnswerResult) {
+	// only one go at it, so don't let a typo use it up.
+	answer, err := question.checkAnswer(answer, true)
+	if err != nil {
+		return err, AnswerResult{Score: player.Score}
+	}
+	player.QuestionsAnswered = append(player.QuestionsAnswered, question.ID)
+	g.lockedAnswers = append(g.lockedAnswers, lockedAnswer{
+		player:  player,
+		answer:  answer,
+		latency: time.Since(g.questionSentAt),
+	})
+	result := AnswerResult{
+		Accepted:    true,
+		Explanation: "answer locked in, points are given out when the question closes",
+		Score:       player.Score,
+		LockedOut:   true,
+	}
+	g.allPlayersAnswered(question.ID)
+	return nil, result
+}
+
+// gradeLocked marks the locked in answers to the question, in the order they were locked in.
+func (g *GameLobby) gradeLocked(question *Question) []grade {
+	if question.lockedIn() {
+		answers := make([]Answer, 0, len(g.lockedAnswers))
+		for _, locked := range g.lockedAnswers {
+			answers = append(answers, locked.answer)
+		}
+		return question.gradeClosest(answers)
+	}
+	grades := make([]grade, 0, len(g.lockedAnswers))
+	for _, locked := range g.lockedAnswers {
+		grades = append(grades, question.grade(locked.answer))
+	}
+	return grades
+}
+
+// revealAnswers closes the current question in RoundEveryone, scoring the locked in answers and telling everyone how it went.
+// strategies where only the first correct answer scores give their points to the quickest correct player.
+// answers with partial credit always get their share.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) revealAnswers() {
+	question := g.Questions[g.CurrentQuestionIndex]
+	scoring := g.scoring()
+	reveal := RevealEvent{
+		QuestionID:   question.ID,
+		Solution:     question.solution(),
+		Distribution: make([]int, len(question.Options)),
+		Correct:      []PlayerSummary{},
+		Awards:       []ScoreAward{},
+	}
+
+	// answers were locked in quickest first, which is the order the positions go in.
+	grades := g.gradeLocked(question)
+	for i, answer := range g.lockedAnswers {
+		grade := grades[i]
+		if answer.answer.Choices != nil {
+			for _, choice := range answer.answer.Choices {
+				reveal.Distribution[choice]++
+			}
+		} else if answer.answer.Choice != nil && *answer.answer.Choice < len(reveal.Distribution) && *answer.answer.Choice >= 0 {
+			reveal.Distribution[*answer.answer.Choice]++
+		}
+		correct := grade.correct()
+		answer.player.recordAnswer(correct, answer.latency)
+		answered := AnalyticsEvent{
+			Type:        AnalyticsAnswerSubmitted,
+			PlayerID:    answer.player.ID,
+			QuestionID:  question.ID,
+			AnswerIndex: grade.choice,
+			Correct:     correct,
+			LatencyMs:   answer.latency.Milliseconds(),
+		}
+		switch {
+		case grade.credit == 0:
+			if penalty := g.penalize(answer.player, question.ID); penalty != nil {
+				answered.Points = penalty.Points
+				reveal.Awards = append(reveal.Awards, *penalty)
+			}
+		case correct:
+			g.correctAnswers++
+			reveal.Correct = append(reveal.Correct, answer.player.Summary())
+			if g.correctAnswers == 1 || scoring.EveryoneScores() {
+				award := g.award(answer.player, question, answer.latency, grade)
+				answer.player.Score += award.Points
+				answered.Points = award.Points
+				reveal.Awards = append(reveal.Awards, award)
+			}
+		default:
+			award := g.award(answer.player, question, answer.latency, grade)
+			answer.player.Score += award.Points
+			answered.Points = award.Points
+			reveal.Awards = append(reveal.Awards, award)
+		}
+		g.record(answered)
+	}
+	if g.correctAnswers == 0 {
+		g.record(AnalyticsEvent{Type: AnalyticsQuestionSkipped, QuestionID: question.ID})
+	}
+
+	g.publish(EventReveal, reveal)
+	g.sendScores(nil)
+	g.setNextQuestionOrEndGame()
+}
@@ -0,0 +1,86 @@
+package game
+
+import (
+	"sort"
+	"time"
+)
+
+// ScoreboardEntry is one player's line on the scoreboard.
+type ScoreboardEntry struct {
+	Rank            int    `json:"rank"` // players on the same score share a rank
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Score           int    `json:"score"`
+	CorrectCount    int    `json:"correctCount"`
+	WrongCount      int    `json:"wrongCount"`
+	AverageAnswerMs int64  `json:"averageAnswerMs"` // 0 until the player has answered something
+}
+
+// Scoreboard returns every player in the lobby ranked by score.
+func (g *GameLobby) Scoreboard() []ScoreboardEntry {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.scoreboard()
+}
+
+func (g *GameLobby) scoreboard() []ScoreboardEntry {
+	contestants := g.contestants()
+	entries := make([]ScoreboardEntry, 0, len(contestants))
+	for _, player := range contestants {
+		entries = append(entries, ScoreboardEntry{
+			ID:              player.ID,
+			Name:            player.Name,
+			Score:           player.Score,
+			CorrectCount:    player.CorrectCount,
+			WrongCount:      player.WrongCount,
+			AverageAnswerMs: player.AverageAnswerTime().Milliseconds(),
+		})
+	}
+
+	// highest score first, with the more accurate and then the quicker player listed first when scores are tied.
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Score != entries[j].Score {
+			return entries[i].Score > entries[j].Score
+		}
+		if entries[i].CorrectCount != entries[j].CorrectCount {
+			return entries[i].CorrectCount > entries[j].CorrectCount
+		}
+		if entries[i].WrongCount != entries[j].WrongCount {
+			return entries[i].WrongCount < entries[j].WrongCount
+		}
+		return entries[i].AverageAnswerMs < entries[j].AverageAnswerMs
+	})
+	for i := range entries {
+		if i > 0 && entries[i].Score == entries[i-1].Score {
+			entries[i].Rank = entries[i-1].Rank
+		} else {
+			entries[i].Rank = i + 1
+		}
+	}
+	return entries
+}
+
+// sendScores pushes the current scoreboard to everyone, along with the points just given out if there were any.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) sendScores(award *ScoreAward) {
+	g.publish(EventScores, ScoresEvent{Scoreboard: g.scoreboard(), Award: award})
+}
+
+// recordAnswer keeps track of how the player is doing for the scoreboard.
+func (p *Player) recordAnswer(correct bool, latency time.Duration) {
+	if correct {
+		p.CorrectCount++
+	} else {
+		p.WrongCount++
+	}
+	p.totalAnswerTime += latency
+}
+
+// AverageAnswerTime is how long the player takes to answer on average, measured from when the question was sent out.
+func (p *Player) AverageAnswerTime() time.Duration {
+	answers := p.CorrectCount + p.WrongCount
+	if answers == 0 {
+		return 0
+	}
+	return p.totalAnswerTime / time.Duration(answers)
+}
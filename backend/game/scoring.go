@@ -0,0 +1,124 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// defaultScoringWindow is how long the speed based strategies give players when the lobby has no question time limit.
+const defaultScoringWindow = 20 * time.Second
+
+// ScoringContext is what a scoring strategy has to go on when a player gets a question right.
+type ScoringContext struct {
+	Question *Question
+	Latency  time.Duration // how long after the question went out the answer came in
+	Window   time.Duration // the question time limit, or defaultScoringWindow when there isn't one
+	Position int           // 1 for the first correct answer to this question, 2 for the second and so on
+}
+
+// ScoreAward is the points given for an answer and why, so players can see how their score was worked out.
+type ScoreAward struct {
+	PlayerID    string `json:"playerId"`
+	QuestionID  string `json:"questionId"`
+	Points      int    `json:"points"`
+	Explanation string `json:"explanation"`
+}
+
+// ScoringStrategy decides how many points a correct answer is worth. each lobby picks one when it is created.
+type ScoringStrategy interface {
+	Name() string
+	// Score works out the points for a correct answer, with Points and Explanation filled in.
+	Score(ctx ScoringContext) ScoreAward
+	// EveryoneScores is true when the question stays open after the first correct answer so the other players can score too.
+	// otherwise the first correct answer takes the points and moves the game on.
+	EveryoneScores() bool
+}
+
+// FlatScoring is the original rule, 10 points to whoever gets the question right first.
+type FlatScoring struct{}
+
+func (FlatScoring) Name() string         { return "flat" }
+func (FlatScoring) EveryoneScores() bool { return false }
+func (FlatScoring) Score(ctx ScoringContext) ScoreAward {
+	return ScoreAward{Points: 10, Explanation: "first correct answer: 10 points"}
+}
+
+// DifficultyScoring gives the first correct answer 10 points for each level of difficulty, unrated questions counting as 1.
+type DifficultyScoring struct{}
+
+func (DifficultyScoring) Name() string         { return "difficulty" }
+func (DifficultyScoring) EveryoneScores() bool { return false }
+func (DifficultyScoring) Score(ctx ScoringContext) ScoreAward {
+	difficulty := ctx.Question.Difficulty
+	if difficulty == 0 {
+		return ScoreAward{Points: 10, Explanation: "first correct answer on an unrated question: 10 points"}
+	}
+	points := 10 * difficulty
+	return ScoreAward{Points: points, Explanation: fmt.Sprintf("first correct answer on a difficulty %d question: 10 x %d = %d points", difficulty, difficulty, points)}
+}
+
+// SpeedScoring gives the first correct answer up to 100 points, dropping steadily to 10 by the end of the scoring window.
+type SpeedScoring struct{}
+
+func (SpeedScoring) Name() string         { return "speed" }
+func (SpeedScoring) EveryoneScores() bool { return false }
+func (SpeedScoring) Score(ctx ScoringContext) ScoreAward {
+	points := 10 + int(90*(1-windowFraction(ctx)))
+	return ScoreAward{Points: points, Explanation: fmt.Sprintf("first correct answer after %s of %s: %d points", roundSeconds(ctx.Latency), roundSeconds(ctx.Window), points)}
+}
+
+// EveryoneScoring lets every player who gets the question right score, from 1000 points for an instant answer
+// down to 500 at the end of the scoring window, so being right matters most and being quick breaks the ties.
+type EveryoneScoring struct{}
+
+func (EveryoneScoring) Name() string         { return "everyone" }
+func (EveryoneScoring) EveryoneScores() bool { return true }
+func (EveryoneScoring) Score(ctx ScoringContext) ScoreAward {
+	points := 500 + int(500*(1-windowFraction(ctx)))
+	return ScoreAward{Points: points, Explanation: fmt.Sprintf("correct answer #%d after %s of %s: %d points", ctx.Position, roundSeconds(ctx.Latency), roundSeconds(ctx.Window), points)}
+}
+
+// windowFraction is how far through the scoring window the answer came in, from 0 to 1.
+func windowFraction(ctx ScoringContext) float64 {
+	if ctx.Window <= 0 || ctx.Latency >= ctx.Window {
+		return 1
+	}
+	if ctx.Latency <= 0 {
+		return 0
+	}
+	return float64(ctx.Latency) / float64(ctx.Window)
+}
+
+func roundSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+var scoringStrategies = map[string]ScoringStrategy{
+	FlatScoring{}.Name():       FlatScoring{},
+	DifficultyScoring{}.Name(): DifficultyScoring{},
+	SpeedScoring{}.Name():      SpeedScoring{},
+	EveryoneScoring{}.Name():   EveryoneScoring{},
+}
+
+// ScoringStrategyByName looks up one of the built in strategies, with a blank name meaning the flat one.
+func ScoringStrategyByName(name string) (ScoringStrategy, error) {
+	if name == "" {
+		return FlatScoring{}, nil
+	}
+	strategy, ok := scoringStrategies[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown scoring strategy %q, expected one of %v", name, ScoringStrategyNames())
+	}
+	return strategy, nil
+}
+
+// ScoringStrategyNames lists the built in strategies in alphabetical order.
+func ScoringStrategyNames() []string {
+	names := make([]string, 0, len(scoringStrategies))
+	for name := range scoringStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,70 @@
+package game
+
+// Spectate subscribes someone who only wants to watch the lobby, like a streamer or a coach.
+// they are not a player, so they cannot answer, are never waited on and take up no room in the lobby.
+// they get everything the players do, and also an answer event saying what the answer was when a question closes without everyone being told.
+// every Spectate should be paired with a StopSpectating once the spectator is done watching.
+func (g *GameLobby) Spectate() *Subscription {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	subscription := g.broadcaster.SubscribeSpectator(subscriberQueueSize, OverflowDisconnect)
+	g.spectators++
+	g.sendRoster()
+	return subscription
+}
+
+// StopSpectating ends a subscription from Spectate.
+func (g *GameLobby) StopSpectating(subscription *Subscription) {
+	g.mutex.Lock()
+	defer g.unlock()
+
+	g.broadcaster.Unsubscribe(subscription)
+	g.spectators--
+	g.sendRoster()
+}
+
+// ResumeSpectating is Resume for a spectator, who has no session but gets the spectator only events as well.
+func (g *GameLobby) ResumeSpectating(seq uint64) ([]*Event, *Event) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.resume(seq, true)
+}
+
+// subscribe makes a player's subscription. players who came in late and are only watching get the spectator only events too.
+func (g *GameLobby) subscribe(spectator bool) *Subscription {
+	if spectator {
+		return g.broadcaster.SubscribeSpectator(subscriberQueueSize, OverflowDisconnect)
+	}
+	return g.broadcaster.Subscribe(subscriberQueueSize, OverflowDisconnect)
+}
+
+// watched is whether anyone is spectating, either through Spectate or by joining late.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) watched() bool {
+	if g.spectators > 0 {
+		return true
+	}
+	for _, player := range g.Players {
+		if player.Spectator {
+			return true
+		}
+	}
+	return false
+}
+
+// sendAnswer lets spectators know the answer to the current question, as it closes.
+// questions that time out or are revealed already tell everyone the answer, so it is only for ones closed by answers coming in.
+// it is only sent while someone is watching, so that players in a lobby nobody spectates never see a gap in the seqs.
+// must be called while holding the lobby mutex.
+func (g *GameLobby) sendAnswer() {
+	if !g.watched() {
+		return
+	}
+	question := g.Questions[g.CurrentQuestionIndex]
+	g.publish(EventAnswer, AnswerEvent{
+		QuestionID:    question.ID,
+		Solution:      question.solution(),
+		CorrectAnswer: question.answerText(),
+	})
+}
@@ -1,18 +1,27 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ProlificLabs/captrivia/analytics"
 	"github.com/ProlificLabs/captrivia/game"
+	"github.com/ProlificLabs/captrivia/questionbank"
 	"github.com/ProlificLabs/captrivia/server"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "validate-questions" {
+		os.Exit(validateQuestions(os.Args[2:]))
+	}
+
 	// Setup the server
 	router, _, err := setupServer()
 	if err != nil {
@@ -33,18 +42,34 @@ func main() {
 // setupServer configures and returns a new Gin instance with all routes.
 // It also returns an error if there is a failure in setting up the server, e.g. loading questions.
 func setupServer() (*gin.Engine, *server.GameServer, error) {
-	// Use the QUESTIONS_FILE environment variable if it exists; otherwise, default to "questions.json"
-	questionsFilePath := os.Getenv("QUESTIONS_FILE")
 	cleanupLobbyIntervalMinutes := os.Getenv("CLEANUP_LOBBIES_EVERY_N_MINUTES")
 
-	questions, err := loadQuestions(questionsFilePath)
+	banks, err := loadQuestionBanks()
 	if err != nil {
 		return nil, nil, err
 	}
 
 	lobbies := game.NewLobbies(getLobbyCleanupIntervalDuration(cleanupLobbyIntervalMinutes))
+	stats, err := setupAnalytics(lobbies)
+	if err != nil {
+		return nil, nil, err
+	}
 	lobbies.StartCleanupRoutine()
-	server := server.NewGameServer(questions, lobbies)
+	server := server.NewGameServer(banks, lobbies)
+	server.Stats = stats
+	server.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if revisionsFile := os.Getenv("QUESTION_REVISIONS_FILE"); revisionsFile != "" {
+		editor, err := questionbank.NewEditor(context.Background(), &questionbank.FileRevisionStore{Path: revisionsFile})
+		if err != nil {
+			return nil, nil, err
+		}
+		server.SetEditor(editor)
+		log.Printf("question edits are kept in %s", revisionsFile)
+	}
+	server.Reloader = questionbank.NewReloader(banks, server.SwapQuestionBank)
+	if interval := getQuestionBankPollInterval(os.Getenv("QUESTION_BANK_POLL_SECONDS")); interval > 0 {
+		server.Reloader.Start(interval)
+	}
 
 	// Create Gin router and setup routes
 	router := gin.Default()
@@ -55,33 +80,178 @@ func setupServer() (*gin.Engine, *server.GameServer, error) {
 	config.AllowAllOrigins = true
 	router.Use(cors.New(config))
 
+	router.GET("/game/questionbanks", server.QuestionBanksHandler)
 	router.POST("/game/newlobby", server.NewLobbyHandler)
+	router.GET("/game/lobbies", server.LobbiesHandler)
+	router.POST("/game/quickmatch", server.QuickMatchHandler)
 	router.GET("/game/joinlobby/:lobbyId", server.JoinLobbyHandler)
 	router.GET("/game/status/:lobbyId", server.GameStatusHandler)
+	router.POST("/game/leavelobby", server.LeaveLobbyHandler)
+	router.POST("/game/ready", server.ReadyHandler)
 	router.POST("/game/start", server.StartGameHandler)
+	router.POST("/game/kick", server.KickHandler)
+	router.POST("/game/host", server.TransferHostHandler)
+	router.POST("/game/settings", server.SettingsHandler)
 	router.POST("/game/answer", server.AnswerHandler)
+	router.POST("/game/rematch", server.RematchHandler)
+	router.GET("/game/history/:lobbyId", server.HistoryHandler)
+	router.GET("/game/events/:lobbyId", server.SpectateHandler)
 	router.GET("/game/events/:lobbyId/:sessionId", server.WsHandler)
+	router.GET("/game/schema/events", server.EventsSchemaHandler)
+	router.GET("/game/metrics", server.MetricsHandler)
+	router.GET("/stats/questions", server.QuestionStatsHandler)
+	router.GET("/stats/lobbies/:lobbyId/players/:id", server.PlayerStatsHandler)
+
+	admin := router.Group("/admin", server.RequireAdmin)
+	admin.GET("/questionbanks/reloads", server.ReloadResultsHandler)
+	admin.POST("/questionbanks/reload", server.ReloadHandler)
+	admin.GET("/questions", server.ListQuestionsHandler)
+	admin.POST("/questions", server.CreateQuestionHandler)
+	admin.PUT("/questions/:id", server.UpdateQuestionHandler)
+	admin.POST("/questions/:id/retire", server.RetireQuestionHandler)
+	admin.GET("/questions/:id/history", server.QuestionHistoryHandler)
 
 	return router, server, nil
 }
 
-func loadQuestions(filePath string) ([]*game.Question, error) {
-	if filePath == "" {
-		filePath = "questions.json"
-	}
-	log.Printf("loading server questions from file: %s", filePath)
+// defaultQuestionBanks are the banks served when QUESTION_BANKS is not set.
+const defaultQuestionBanks = "cap-table=questions.json,easy=easyquestions.json"
 
-	fileBytes, err := os.ReadFile(filePath)
+// loadQuestionBanks loads the question banks named in QUESTION_BANKS, a comma separated list of name=location pairs
+// where the location is a json or csv file, a directory of them, or "postgres:table". the first bank is the default one.
+// if only QUESTIONS_FILE is set, that file is served as the one and only bank, the way it used to be.
+// the banks are validated as they load, and any errors in them stop the server from starting.
+func loadQuestionBanks() ([]questionbank.Bank, error) {
+	specs, err := questionbank.ParseSpecs(questionBankList())
+	if err != nil {
+		return nil, err
+	}
+	banks, err := loadBanks(specs)
 	if err != nil {
 		return nil, err
 	}
+	for _, bank := range banks {
+		log.Printf("loaded %d questions into question bank %s from %s", len(bank.Questions), bank.Name, bank.Source.Describe())
+	}
+
+	problems := questionbank.ValidateBanks(banks)
+	for _, problem := range problems {
+		log.Print(problem)
+	}
+	if questionbank.Fatal(problems) {
+		return nil, fmt.Errorf("the question banks have errors, see above or run validate-questions")
+	}
+	return banks, nil
+}
+
+func questionBankList() string {
+	bankList := os.Getenv("QUESTION_BANKS")
+	if bankList == "" {
+		bankList = defaultQuestionBanks
+		if questionsFilePath := os.Getenv("QUESTIONS_FILE"); questionsFilePath != "" {
+			bankList = "cap-table=" + questionsFilePath
+		}
+	}
+	return bankList
+}
+
+// loadBanks loads the banks, connecting to the database only if one of them is kept there.
+func loadBanks(specs []questionbank.Spec) ([]questionbank.Bank, error) {
+	var db *sql.DB
+	if questionbank.NeedsDB(specs) && os.Getenv("DB_HOST") != "" {
+		var err error
+		if db, err = sql.Open("postgres", postgresDSN()); err != nil {
+			return nil, err
+		}
+	}
 
-	var questions []*game.Question
-	if err := json.Unmarshal(fileBytes, &questions); err != nil {
+	return questionbank.Load(context.Background(), specs, db)
+}
+
+// validateQuestions is the validate-questions command. it checks the banks given as arguments, either name=location or just
+// a location, or the ones the server would load if there are none, and prints every problem found.
+// it exits with 1 if there are any errors, like the server would refuse to start, or 2 if the banks could not be loaded at all.
+func validateQuestions(args []string) int {
+	bankList := questionBankList()
+	if len(args) > 0 {
+		named := make([]string, 0, len(args))
+		for _, arg := range args {
+			if !strings.Contains(arg, "=") {
+				arg = arg + "=" + arg
+			}
+			named = append(named, arg)
+		}
+		bankList = strings.Join(named, ",")
+	}
+	specs, err := questionbank.ParseSpecs(bankList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	banks, err := loadBanks(specs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	problems := questionbank.ValidateBanks(banks)
+	errorCount := 0
+	for _, problem := range problems {
+		fmt.Println(problem)
+		if problem.Severity == questionbank.SeverityError {
+			errorCount++
+		}
+	}
+	questionCount := 0
+	for _, bank := range banks {
+		questionCount += len(bank.Questions)
+	}
+	fmt.Printf("checked %d questions in %d banks: %d errors, %d warnings\n", questionCount, len(banks), errorCount, len(problems)-errorCount)
+	if errorCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// postgresDSN builds the connection string for the database from the DB_ environment variables.
+func postgresDSN() string {
+	sslMode := os.Getenv("DB_SSLMODE")
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"), sslMode)
+}
+
+// setupAnalytics records analytics to postgres when DB_HOST is set, otherwise analytics are not recorded at all.
+// the returned querier serves the stats endpoints and is nil when analytics are off.
+func setupAnalytics(lobbies *game.Lobbies) (analytics.Querier, error) {
+	host := os.Getenv("DB_HOST")
+	if host == "" {
+		log.Printf("DB_HOST is not set, analytics will not be recorded")
+		return nil, nil
+	}
+	store, err := analytics.OpenPostgres(postgresDSN())
+	if err != nil {
 		return nil, err
 	}
+	// the database may still be starting up (eg under docker compose) so give it a little while before giving up.
+	for attempt := 1; ; attempt++ {
+		err = store.Migrate(context.Background())
+		if err == nil {
+			break
+		}
+		if attempt == 10 {
+			return nil, fmt.Errorf("analytics database migration failed: %w", err)
+		}
+		log.Printf("analytics database not ready (attempt %d): %v", attempt, err)
+		time.Sleep(2 * time.Second)
+	}
 
-	return questions, nil
+	// batches of 100 keep each insert well under postgres' limit on statement parameters.
+	lobbies.SetAnalyticsSink(analytics.NewAsyncSink(store, 10000, 100, time.Second))
+	log.Printf("recording analytics to postgres at %s", host)
+	return store, nil
 }
 
 func getLobbyCleanupIntervalDuration(settingFromEnv string) time.Duration {
@@ -93,3 +263,12 @@ func getLobbyCleanupIntervalDuration(settingFromEnv string) time.Duration {
 	log.Printf("will clean up old lobbies every %d minutes", minutes)
 	return time.Duration(minutes) * time.Minute
 }
+
+// getQuestionBankPollInterval is how often to check the question banks for changes, 5 seconds unless set. 0 turns the checks off.
+func getQuestionBankPollInterval(settingFromEnv string) time.Duration {
+	seconds, err := strconv.Atoi(settingFromEnv)
+	if err != nil || seconds < 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(seconds) * time.Second
+}
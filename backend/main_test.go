@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/ProlificLabs/captrivia/analytics"
 	"github.com/ProlificLabs/captrivia/game"
+	"github.com/ProlificLabs/captrivia/questionbank"
 	"github.com/ProlificLabs/captrivia/server"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -21,6 +26,7 @@ import (
 var testRouter *gin.Engine
 var testHttpServer *httptest.Server
 var testGameServer *server.GameServer
+var testAnalytics = analytics.NewMemoryStore()
 
 // TestMain is called before any test runs.
 // It allows us to set up things and also clean up after all tests have been run.
@@ -33,6 +39,8 @@ func TestMain(m *testing.M) {
 	if err != nil {
 		log.Fatal("Failed to set up test server:", err)
 	}
+	testGameServer.Lobbies.SetAnalyticsSink(testAnalytics)
+	testGameServer.Stats = testAnalytics
 
 	// Start a new httptest server using the testRouter.
 	testHttpServer = httptest.NewServer(testRouter)
@@ -80,6 +88,25 @@ func TestNewLobbyHandler(t *testing.T) {
 	}
 }
 
+func TestNewLobbyRefusesNegativeSettings(t *testing.T) {
+	for _, setting := range []string{"questionCount", "countdownMs", "questionTimeoutMs"} {
+		t.Run(setting, func(t *testing.T) {
+			resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(fmt.Sprintf(`{"%s":-1}`, setting)))
+			if err != nil {
+				t.Fatalf("Failed to make request: %v", err)
+			}
+			defer resp.Body.Close()
+			var response struct {
+				Code server.ErrorCode `json:"code"`
+			}
+			json.NewDecoder(resp.Body).Decode(&response)
+			if resp.StatusCode != http.StatusBadRequest || response.Code != server.CodeInvalidRequest {
+				t.Errorf("expected %d %q, got %v %q", http.StatusBadRequest, server.CodeInvalidRequest, resp.Status, response.Code)
+			}
+		})
+	}
+}
+
 func TestJoinLobbyHandler(t *testing.T) {
 	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(fmt.Sprintf(`{"questionCount":%d, "countdownMs":%d}`, 5, 100)))
 	if err != nil {
@@ -134,6 +161,7 @@ func TestFullGameSinglePlayer(t *testing.T) {
 	var response struct {
 		LobbyId   string `json:"lobbyId"`
 		SessionId string `json:"sessionId"`
+		PlayerId  string `json:"playerId"`
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(&response)
@@ -253,15 +281,17 @@ func TestFullGameSinglePlayer(t *testing.T) {
 		t.Fatalf("wrong number of winners")
 	}
 
-	if gameStatusResponse.Winners[0] != response.SessionId {
+	if gameStatusResponse.Winners[0].ID != response.PlayerId {
 		t.Fatalf("winner had an unexpected sessionid")
 	}
 }
 
 // TODO use this in all the tests for new lobby/join lobby response handling.
 type joinGameResponse struct {
-	LobbyId   string `json:"lobbyId"`
-	SessionId string `json:"sessionId"`
+	LobbyId    string `json:"lobbyId"`
+	SessionId  string `json:"sessionId"`
+	PlayerId   string `json:"playerId"`
+	PlayerName string `json:"playerName"`
 }
 
 func TestFullGameMultiPlayer(t *testing.T) {
@@ -291,7 +321,6 @@ func TestFullGameMultiPlayer(t *testing.T) {
 		t.Errorf("Response should contain a sessionId")
 	}
 	player1SessionId := response.SessionId
-	_ = player1SessionId
 
 	// Now join this lobby as a 2nd player.
 	resp, err = http.Get(testHttpServer.URL + "/game/joinlobby/" + response.LobbyId)
@@ -309,8 +338,19 @@ func TestFullGameMultiPlayer(t *testing.T) {
 	}
 	t.Logf("%+v", p2response)
 	player2SessionId := p2response.SessionId
+	player2Id := p2response.PlayerId
 
+	// only the host, who created the lobby, gets to start the game.
 	resp, err = http.Post(testHttpServer.URL+"/game/start", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s","sessionId":"%s"}`, response.LobbyId, player2SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status Forbidden for a player who is not the host; got %v", resp.Status)
+	}
+
+	resp, err = http.Post(testHttpServer.URL+"/game/start", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s","sessionId":"%s"}`, response.LobbyId, player1SessionId)))
 	if err != nil {
 		t.Fatalf("Failed to start a new game: %v", err)
 	}
@@ -385,7 +425,1222 @@ func TestFullGameMultiPlayer(t *testing.T) {
 		t.Fatalf("wrong number of winners")
 	}
 	//and that it is the expected winner.
-	if gameStatusResponse.Winners[0] != player2SessionId {
+	if gameStatusResponse.Winners[0].ID != player2Id {
 		t.Fatalf("winner had an unexpected sessionid")
 	}
+	//and the scoreboard has everyone, ranked.
+	if len(gameStatusResponse.Scoreboard) != 2 {
+		t.Fatalf("expected both players on the scoreboard, got %+v", gameStatusResponse.Scoreboard)
+	}
+	if first, second := gameStatusResponse.Scoreboard[0], gameStatusResponse.Scoreboard[1]; first.ID != player2Id || first.CorrectCount != 2 || second.Rank != 2 || second.Score != 10 {
+		t.Fatalf("unexpected scoreboard %+v", gameStatusResponse.Scoreboard)
+	}
+}
+
+func TestJoinLobbyWithPlayerName(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":3, "countdownMs":100, "playerName":"Alice"}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if response.PlayerName != "Alice" || response.PlayerId == "" || response.PlayerId == response.SessionId {
+		t.Fatalf("expected a public player id and our chosen name, got %+v", response)
+	}
+
+	// the name is already used in this lobby
+	resp, err = http.Get(testHttpServer.URL + "/game/joinlobby/" + response.LobbyId + "?name=alice")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("Expected status Conflict; got %v", resp.Status)
+	}
+
+	resp, err = http.Get(testHttpServer.URL + "/game/joinlobby/" + response.LobbyId + "?name=Bob")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	roster := lobby.Roster()
+	if len(roster) != 2 || roster[0].Name != "Alice" || roster[1].Name != "Bob" {
+		t.Fatalf("unexpected roster: %+v", roster)
+	}
+}
+
+// readAck reads messages off the websocket until the acknowledgement for requestId shows up, skipping lobby messages in between.
+func readAck(t *testing.T, conn *websocket.Conn, requestId string) server.CommandAck {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var message map[string]interface{}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed reading from websocket waiting for ack %s: %v", requestId, err)
+		}
+		if message["type"] != "ack" || message["requestId"] != requestId {
+			continue
+		}
+		raw, _ := json.Marshal(message)
+		var ack server.CommandAck
+		json.Unmarshal(raw, &ack)
+		return ack
+	}
+}
+
+func TestWebsocketCommands(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+
+	wsURL := strings.Replace(testHttpServer.URL, "http", "ws", 1) + "/game/events/" + response.LobbyId + "/" + response.SessionId
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to open websocket: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: server.CommandPing, RequestID: "ping-1"})
+	if ack := readAck(t, conn, "ping-1"); !ack.OK {
+		t.Fatalf("ping should be acknowledged, got %+v", ack)
+	}
+
+	conn.WriteJSON(server.ClientCommand{Version: 99, Type: server.CommandPing, RequestID: "old-client"})
+	if ack := readAck(t, conn, "old-client"); ack.OK || !strings.Contains(ack.Error, "protocol version") || ack.Code != server.CodeUnsupportedProtocol {
+		t.Fatalf("expected an unsupported version to be refused, got %+v", ack)
+	}
+
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: "dance", RequestID: "unknown"})
+	if ack := readAck(t, conn, "unknown"); ack.OK {
+		t.Fatalf("expected an unknown command to be refused, got %+v", ack)
+	}
+
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: server.CommandReady, RequestID: "ready-1", Data: json.RawMessage(`{"ready":true}`)})
+	if ack := readAck(t, conn, "ready-1"); !ack.OK {
+		t.Fatalf("ready should be acknowledged, got %+v", ack)
+	}
+
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: server.CommandChat, RequestID: "chat-1", Data: json.RawMessage(`{"text":"good luck"}`)})
+	if ack := readAck(t, conn, "chat-1"); !ack.OK {
+		t.Fatalf("chat should be acknowledged, got %+v", ack)
+	}
+
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: server.CommandStart, RequestID: "start-1"})
+	if ack := readAck(t, conn, "start-1"); !ack.OK {
+		t.Fatalf("start should be acknowledged, got %+v", ack)
+	}
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: server.CommandStart, RequestID: "start-2"})
+	if ack := readAck(t, conn, "start-2"); ack.OK {
+		t.Fatalf("starting twice should fail, got %+v", ack)
+	}
+
+	// wait for the question to come over the socket, then answer it over the socket too.
+	var question struct {
+		ID string `json:"id"`
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for question.ID == "" {
+		var message struct {
+			Type game.EventType `json:"type"`
+			Data struct {
+				Question struct {
+					ID string `json:"id"`
+				} `json:"question"`
+			} `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed waiting for the question: %v", err)
+		}
+		if message.Type == game.EventQuestion {
+			question.ID = message.Data.Question.ID
+		}
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	correctIndex := lobby.Questions[0].CorrectIndex
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: server.CommandAnswer, RequestID: "answer-1", Data: json.RawMessage(fmt.Sprintf(`{"questionId":"%s","answer":%d}`, question.ID, correctIndex))})
+	ack := readAck(t, conn, "answer-1")
+	if !ack.OK || ack.Data.(map[string]interface{})["points"] != float64(10) {
+		t.Fatalf("expected 10 points for the answer, got %+v", ack)
+	}
+	if lobby.GameStatus().State != game.Ended {
+		t.Fatalf("answering the only question should have ended the game")
+	}
+}
+
+func TestEventsSchemaCoversEveryEventType(t *testing.T) {
+	resp, err := http.Get(testHttpServer.URL + "/game/schema/events")
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]struct {
+				Enum []string `json:"enum"`
+			} `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	documented := schema.Defs["Event"].Properties["type"].Enum
+	for _, eventType := range game.EventTypes {
+		found := false
+		for _, d := range documented {
+			found = found || d == string(eventType)
+		}
+		if !found {
+			t.Errorf("event type %q is missing from the schema", eventType)
+		}
+	}
+	if len(documented) != len(game.EventTypes) {
+		t.Errorf("schema documents %d event types but the game has %d", len(documented), len(game.EventTypes))
+	}
+}
+
+func TestWebsocketResume(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+
+	// start the game without anyone listening, as if the page was reloading at the time.
+	resp, err = http.Post(testHttpServer.URL+"/game/start", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s","sessionId":"%s"}`, response.LobbyId, response.SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to start a new game: %v", err)
+	}
+	defer resp.Body.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	wsURL := strings.Replace(testHttpServer.URL, "http", "ws", 1) + "/game/events/" + response.LobbyId + "/" + response.SessionId + "?since=0"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to open websocket: %v", err)
+	}
+	defer conn.Close()
+
+	// everything from the start of the lobby is replayed in order, then the snapshot, with nothing repeated after it.
+	expected := []game.EventType{game.EventRoster, game.EventCountdown, game.EventQuestion, game.EventSnapshot}
+	var lastSeq uint64
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for i, eventType := range expected {
+		var event struct {
+			Type game.EventType `json:"type"`
+			Seq  uint64         `json:"seq"`
+		}
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("Failed reading replayed events: %v", err)
+		}
+		if event.Type != eventType {
+			t.Fatalf("expected event %d to be %s, got %s", i, eventType, event.Type)
+		}
+		if event.Type != game.EventSnapshot && event.Seq != lastSeq+1 {
+			t.Fatalf("expected seq %d, got %d", lastSeq+1, event.Seq)
+		}
+		lastSeq = event.Seq
+	}
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	var extra map[string]interface{}
+	if err := conn.ReadJSON(&extra); err == nil {
+		t.Fatalf("expected no duplicate events after the snapshot, got %+v", extra)
+	}
+
+	// a bad since is refused before upgrading.
+	resp, err = http.Get(testHttpServer.URL + "/game/events/" + response.LobbyId + "/" + response.SessionId + "?since=later")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status Bad Request; got %v", resp.Status)
+	}
+}
+
+func TestAnalyticsRecordedForAGame(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":2, "countdownMs":10}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	if err := lobby.StartGame(testGameServer.Questions()); err != nil {
+		t.Fatalf("Failed to start game: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// one wrong answer (which skips the question, being the only player) and then one right one.
+	first, second := lobby.Questions[0], lobby.Questions[1]
+	lobby.SubmitAnswer(response.SessionId, first.ID, (first.CorrectIndex+1)%len(first.Options))
+	lobby.SubmitAnswer(response.SessionId, second.ID, second.CorrectIndex)
+
+	var events []game.AnalyticsEvent
+	for _, event := range testAnalytics.Events() {
+		if event.LobbyID == response.LobbyId {
+			events = append(events, event)
+		}
+	}
+	expected := []game.AnalyticsEventType{
+		game.AnalyticsLobbyCreated,
+		game.AnalyticsGameStarted,
+		game.AnalyticsAnswerSubmitted,
+		game.AnalyticsQuestionSkipped,
+		game.AnalyticsAnswerSubmitted,
+		game.AnalyticsGameEnded,
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d analytics events, got %+v", len(expected), events)
+	}
+	for i, eventType := range expected {
+		if events[i].Type != eventType {
+			t.Errorf("expected event %d to be %s, got %s", i, eventType, events[i].Type)
+		}
+	}
+	if events[2].Correct || events[2].QuestionID != first.ID || events[2].PlayerID != response.PlayerId {
+		t.Errorf("unexpected wrong answer event %+v", events[2])
+	}
+	if !events[4].Correct || events[4].Points != 10 || events[4].QuestionID != second.ID {
+		t.Errorf("unexpected right answer event %+v", events[4])
+	}
+	if !events[5].Won || events[5].Points != 10 {
+		t.Errorf("expected the only player to have won with 10 points, got %+v", events[5])
+	}
+}
+
+func TestStatsEndpoints(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	if err := lobby.StartGame(testGameServer.Questions()); err != nil {
+		t.Fatalf("Failed to start game: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	question := lobby.Questions[0]
+	wrongIndex := (question.CorrectIndex + 1) % len(question.Options)
+	lobby.SubmitAnswer(response.SessionId, question.ID, wrongIndex)
+
+	resp, err = http.Get(testHttpServer.URL + "/stats/questions")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var questionStats struct {
+		Questions []struct {
+			QuestionID                string  `json:"questionId"`
+			QuestionText              string  `json:"questionText"`
+			CorrectRate               float64 `json:"correctRate"`
+			MostCommonWrongAnswerText string  `json:"mostCommonWrongAnswerText"`
+		} `json:"questions"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&questionStats); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	found := false
+	for _, s := range questionStats.Questions {
+		if s.QuestionID == question.ID {
+			found = true
+			if s.QuestionText != question.QuestionText || s.MostCommonWrongAnswerText == "" {
+				t.Errorf("expected question and wrong answer text to be filled in, got %+v", s)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected stats for question %s, got %+v", question.ID, questionStats.Questions)
+	}
+
+	resp, err = http.Get(testHttpServer.URL + "/stats/lobbies/" + response.LobbyId + "/players/" + response.PlayerId)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var playerStats struct {
+		Answers  int     `json:"answers"`
+		Accuracy float64 `json:"accuracy"`
+		Games    int     `json:"games"`
+		WinRate  float64 `json:"winRate"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&playerStats); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	// being the only player they still win, even with nothing scored.
+	if playerStats.Answers != 1 || playerStats.Accuracy != 0 || playerStats.Games != 1 || playerStats.WinRate != 1 {
+		t.Errorf("unexpected player stats %+v", playerStats)
+	}
+
+	resp, err = http.Get(testHttpServer.URL + "/stats/lobbies/" + response.LobbyId + "/players/nobody")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status Not Found; got %v", resp.Status)
+	}
+}
+
+func TestNewLobbyWithQuestionFilter(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":2, "countdownMs":10, "categories":["employee equity"], "difficultyMix":{"1":1,"2":1}}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	if err := lobby.StartGame(testGameServer.Questions()); err != nil {
+		t.Fatalf("Failed to start game: %v", err)
+	}
+	if len(lobby.Questions) != 2 {
+		t.Fatalf("expected 2 questions, got %d", len(lobby.Questions))
+	}
+	for _, q := range lobby.Questions {
+		if q.Category != "employee equity" {
+			t.Errorf("expected only employee equity questions, got %+v", q)
+		}
+	}
+
+	// there are nowhere near 10 employee equity questions.
+	resp, err = http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":10, "countdownMs":10, "categories":["employee equity"]}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status Unprocessable Entity; got %v", resp.Status)
+	}
+	var errorResponse struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	json.NewDecoder(resp.Body).Decode(&errorResponse)
+	if !strings.Contains(errorResponse.Error, "only 4 match") || errorResponse.Code != string(server.CodeNotEnoughQuestions) {
+		t.Errorf("expected the error to say how many questions match, got %+v", errorResponse)
+	}
+}
+
+func TestWrongAnswerResponse(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":2, "countdownMs":10, "wrongAnswerPenalty":5, "answerPolicy":"retry", "retryCooldownMs":1000}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	if err := lobby.StartGame(testGameServer.Questions()); err != nil {
+		t.Fatalf("Failed to start game: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	question := lobby.Questions[0]
+	resp, err = http.Post(testHttpServer.URL+"/game/answer", "application/json", strings.NewReader(fmt.Sprintf(`{"sessionId":"%s", "lobbyId":"%s", "questionId":"%s", "answer":%d}`, response.SessionId, response.LobbyId, question.ID, (question.CorrectIndex+1)%len(question.Options))))
+	if err != nil {
+		t.Fatalf("Failed to submit answer: %v", err)
+	}
+	defer resp.Body.Close()
+	var answerResponse struct {
+		Accepted     bool   `json:"accepted"`
+		Correct      bool   `json:"correct"`
+		Points       int    `json:"points"`
+		Score        int    `json:"score"`
+		RetryAfterMs int64  `json:"retryAfterMs"`
+		LockedOut    bool   `json:"lockedOut"`
+		Error        string `json:"error"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&answerResponse); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if !answerResponse.Accepted || answerResponse.Correct || answerResponse.Points != -5 || answerResponse.Score != -5 ||
+		answerResponse.RetryAfterMs != 1000 || answerResponse.LockedOut || answerResponse.Error == "" {
+		t.Errorf("unexpected answer response %+v", answerResponse)
+	}
+
+	// retrying is not allowed with answers locked in.
+	resp, err = http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":2, "roundMode":"everyone", "answerPolicy":"retry"}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status Bad Request; got %v", resp.Status)
+	}
+}
+
+func TestEventsSchemaCoversEveryErrorCode(t *testing.T) {
+	resp, err := http.Get(testHttpServer.URL + "/game/schema/events")
+	if err != nil {
+		t.Fatalf("Failed to get schema: %v", err)
+	}
+	defer resp.Body.Close()
+	var schema struct {
+		Defs struct {
+			ErrorCode struct {
+				Enum []string `json:"enum"`
+			}
+		} `json:"$defs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+
+	documented := schema.Defs.ErrorCode.Enum
+	for _, code := range server.ErrorCodes {
+		found := false
+		for _, d := range documented {
+			found = found || d == string(code)
+		}
+		if !found {
+			t.Errorf("error code %q is missing from the schema", code)
+		}
+	}
+	if len(documented) != len(server.ErrorCodes) {
+		t.Errorf("schema documents %d error codes but the server has %d", len(documented), len(server.ErrorCodes))
+	}
+}
+
+// postAnswer submits an answer over http and returns the status and error code that came back.
+func postAnswer(t *testing.T, lobbyId, sessionId, questionId string, answer int) (int, server.ErrorCode) {
+	resp, err := http.Post(testHttpServer.URL+"/game/answer", "application/json", strings.NewReader(fmt.Sprintf(`{"sessionId":"%s", "lobbyId":"%s", "questionId":"%s", "answer":%d}`, sessionId, lobbyId, questionId, answer)))
+	if err != nil {
+		t.Fatalf("Failed to submit answer: %v", err)
+	}
+	defer resp.Body.Close()
+	var answerResponse struct {
+		Code server.ErrorCode `json:"code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answerResponse); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	return resp.StatusCode, answerResponse.Code
+}
+
+func TestAnswerErrorCodes(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	resp, err = http.Get(testHttpServer.URL + "/game/joinlobby/" + response.LobbyId)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var second joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&second); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+
+	expect := func(what string, status int, code server.ErrorCode, gotStatus int, gotCode server.ErrorCode) {
+		t.Helper()
+		if gotStatus != status || gotCode != code {
+			t.Errorf("%s: expected %d %q, got %d %q", what, status, code, gotStatus, gotCode)
+		}
+	}
+
+	status, code := postAnswer(t, "no-such-lobby", response.SessionId, "", 0)
+	expect("unknown lobby", http.StatusNotFound, server.CodeLobbyNotFound, status, code)
+	status, code = postAnswer(t, response.LobbyId, response.SessionId, "", 0)
+	expect("not started", http.StatusConflict, server.CodeGameNotStarted, status, code)
+
+	if err := lobby.StartGame(testGameServer.Questions()); err != nil {
+		t.Fatalf("Failed to start game: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	question := lobby.Questions[0]
+	wrongIndex := (question.CorrectIndex + 1) % len(question.Options)
+
+	status, code = postAnswer(t, response.LobbyId, "no-such-session", question.ID, 0)
+	expect("unknown player", http.StatusNotFound, server.CodePlayerNotFound, status, code)
+	status, code = postAnswer(t, response.LobbyId, response.SessionId, "no-such-question", 0)
+	expect("wrong question", http.StatusConflict, server.CodeWrongQuestion, status, code)
+	status, code = postAnswer(t, response.LobbyId, response.SessionId, question.ID, wrongIndex)
+	expect("incorrect", http.StatusOK, server.CodeIncorrectAnswer, status, code)
+	status, code = postAnswer(t, response.LobbyId, response.SessionId, question.ID, question.CorrectIndex)
+	expect("already answered", http.StatusConflict, server.CodeAlreadyAnswered, status, code)
+	status, code = postAnswer(t, response.LobbyId, second.SessionId, question.ID, question.CorrectIndex)
+	expect("correct", http.StatusOK, "", status, code)
+	status, code = postAnswer(t, response.LobbyId, second.SessionId, question.ID, question.CorrectIndex)
+	expect("ended", http.StatusConflict, server.CodeGameEnded, status, code)
+
+	// the other handlers use the same codes.
+	resp, err = http.Post(testHttpServer.URL+"/game/start", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s"}`, response.LobbyId, response.SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var startResponse struct {
+		Code server.ErrorCode `json:"code"`
+	}
+	json.NewDecoder(resp.Body).Decode(&startResponse)
+	expect("start again", http.StatusConflict, server.CodeGameAlreadyStarted, resp.StatusCode, startResponse.Code)
+}
+
+func TestReadyCheckOverREST(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10, "readyPolicy":"all", "autoStart":true}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+
+	resp, err = http.Post(testHttpServer.URL+"/game/start", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s"}`, response.LobbyId, response.SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var startResponse struct {
+		Code server.ErrorCode `json:"code"`
+	}
+	json.NewDecoder(resp.Body).Decode(&startResponse)
+	if resp.StatusCode != http.StatusConflict || startResponse.Code != server.CodePlayersNotReady {
+		t.Fatalf("expected the start to be refused until the player is ready, got %v %q", resp.Status, startResponse.Code)
+	}
+
+	resp, err = http.Post(testHttpServer.URL+"/game/ready", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "ready":true}`, response.LobbyId, response.SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	if state := lobby.GameStatus().State; state != game.Starting && state != game.Started {
+		t.Errorf("expected the game to start by itself once the only player was ready, got state %v", state)
+	}
+
+	// a quorum the lobby does not have room for could never be reached, whether it is set up that way or changed to it later.
+	resp, err = http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10, "readyPolicy":"quorum", "readyQuorum":3, "maxPlayers":2}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a quorum bigger than the lobby to be refused, got %v", resp.Status)
+	}
+
+	resp, err = http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10, "readyPolicy":"quorum", "readyQuorum":3}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	resp, err = http.Post(testHttpServer.URL+"/game/settings", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "maxPlayers":2}`, response.LobbyId, response.SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected shrinking the lobby below its quorum to be refused, got %v", resp.Status)
+	}
+}
+
+func TestHostActionsOverREST(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10, "password":"hunter2", "visibility":"private", "categories":["fundraising"]}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var host joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&host); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	resp, err = http.Get(testHttpServer.URL + "/game/joinlobby/" + host.LobbyId + "?password=hunter2")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var guest joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&guest); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+
+	post := func(path, body string) (int, server.ErrorCode) {
+		resp, err := http.Post(testHttpServer.URL+path, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		var response struct {
+			Code server.ErrorCode `json:"code"`
+		}
+		json.NewDecoder(resp.Body).Decode(&response)
+		return resp.StatusCode, response.Code
+	}
+
+	status, code := post("/game/kick", fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "playerId":"%s"}`, host.LobbyId, guest.SessionId, host.PlayerId))
+	if status != http.StatusForbidden || code != server.CodeNotHost {
+		t.Errorf("expected a guest kicking the host to be refused, got %d %q", status, code)
+	}
+	status, code = post("/game/settings", fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "questionCount":2}`, host.LobbyId, guest.SessionId))
+	if status != http.StatusForbidden || code != server.CodeNotHost {
+		t.Errorf("expected a guest changing settings to be refused, got %d %q", status, code)
+	}
+	status, code = post("/game/settings", fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "questionCount":2, "countdownMs":10, "scoring":"nope"}`, host.LobbyId, host.SessionId))
+	if status != http.StatusBadRequest || code != server.CodeInvalidRequest {
+		t.Errorf("expected invalid settings to be refused, got %d %q", status, code)
+	}
+	status, _ = post("/game/settings", fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "questionCount":2, "countdownMs":10}`, host.LobbyId, host.SessionId))
+	if status != http.StatusOK {
+		t.Errorf("expected the host to be able to change settings, got %d", status)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(host.LobbyId)
+	if lobby.Settings().QuestionCount != 2 {
+		t.Errorf("expected the question count to have changed, got %+v", lobby.Settings())
+	}
+	// anything left out of the body stays as it was.
+	status, _ = post("/game/settings", fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "questionCount":3}`, host.LobbyId, host.SessionId))
+	settings := lobby.Settings()
+	if status != http.StatusOK || settings.QuestionCount != 3 || settings.Countdown != 10 || settings.Password != "hunter2" ||
+		settings.JoinRules.Visibility != game.Private || len(settings.QuestionFilter.Categories) != 1 {
+		t.Errorf("expected only the question count to change, got %d %+v", status, settings)
+	}
+	status, _ = post("/game/settings", fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "password":"", "categories":[]}`, host.LobbyId, host.SessionId))
+	if settings := lobby.Settings(); status != http.StatusOK || settings.Password != "" || len(settings.QuestionFilter.Categories) != 0 || settings.QuestionCount != 3 {
+		t.Errorf("expected the password and categories to be cleared, got %d %+v", status, settings)
+	}
+
+	status, _ = post("/game/host", fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "playerId":"%s"}`, host.LobbyId, host.SessionId, guest.PlayerId))
+	if status != http.StatusOK {
+		t.Fatalf("expected the host to be able to hand over, got %d", status)
+	}
+	status, _ = post("/game/kick", fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s", "playerId":"%s"}`, host.LobbyId, guest.SessionId, host.PlayerId))
+	if status != http.StatusOK {
+		t.Fatalf("expected the new host to be able to kick the old one, got %d", status)
+	}
+	if _, err := lobby.GetPlayer(host.SessionId); err != game.ErrPlayerNotFound {
+		t.Errorf("expected the kicked player to be gone, got %v", err)
+	}
+}
+
+func TestJoinLobbyErrors(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10, "maxPlayers":2, "password":"hunter2", "visibility":"private"}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response struct {
+		joinGameResponse
+		PasswordProtected bool `json:"passwordProtected"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if !response.PasswordProtected {
+		t.Error("expected the lobby to say it needs a password")
+	}
+
+	join := func(query string) (int, server.ErrorCode) {
+		resp, err := http.Get(testHttpServer.URL + "/game/joinlobby/" + response.LobbyId + query)
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		var joinResponse struct {
+			Code server.ErrorCode `json:"code"`
+		}
+		json.NewDecoder(resp.Body).Decode(&joinResponse)
+		return resp.StatusCode, joinResponse.Code
+	}
+	if status, code := join("?password=nope"); status != http.StatusForbidden || code != server.CodeWrongPassword {
+		t.Errorf("expected a wrong password to be refused, got %d %q", status, code)
+	}
+	if status, _ := join("?password=hunter2"); status != http.StatusOK {
+		t.Errorf("expected the right password to get in, got %d", status)
+	}
+	if status, code := join("?password=hunter2"); status != http.StatusConflict || code != server.CodeLobbyFull {
+		t.Errorf("expected a full lobby to turn the player away, got %d %q", status, code)
+	}
+
+	resp, err = http.Post(testHttpServer.URL+"/game/start", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s"}`, response.LobbyId, response.SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if status, code := join("?password=hunter2"); status != http.StatusConflict || code != server.CodeGameAlreadyStarted {
+		t.Errorf("expected a started game to turn the player away, got %d %q", status, code)
+	}
+}
+
+func TestSpectatorWebsocket(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+
+	wsURL := strings.Replace(testHttpServer.URL, "http", "ws", 1) + "/game/events/" + response.LobbyId
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to open websocket: %v", err)
+	}
+	defer conn.Close()
+
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: server.CommandPing, RequestID: "ping-1"})
+	if ack := readAck(t, conn, "ping-1"); !ack.OK {
+		t.Fatalf("ping should be acknowledged, got %+v", ack)
+	}
+	conn.WriteJSON(server.ClientCommand{Version: server.ProtocolVersion, Type: server.CommandAnswer, RequestID: "answer-1", Data: json.RawMessage(`{"questionId":"q1","answer":0}`)})
+	if ack := readAck(t, conn, "answer-1"); ack.OK || ack.Code != server.CodeSpectator {
+		t.Fatalf("expected a spectator not to be able to answer, got %+v", ack)
+	}
+
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	if err := lobby.StartGame(testGameServer.Questions()); err != nil {
+		t.Fatalf("Failed to start the game: %v", err)
+	}
+	for lobby.GameStatus().State != game.Started {
+		time.Sleep(time.Millisecond)
+	}
+	question := lobby.Questions[0]
+	postAnswer(t, response.LobbyId, response.SessionId, question.ID, question.CorrectIndex)
+
+	// the spectator sees the game go by, with the answer at the end of the question.
+	seen := map[game.EventType]bool{}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for !seen[game.EventGameOver] {
+		var message struct {
+			Type game.EventType `json:"type"`
+			Data struct {
+				CorrectIndex int `json:"correctIndex"`
+			} `json:"data"`
+		}
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("Failed waiting for the game to end: %v", err)
+		}
+		seen[message.Type] = true
+		if message.Type == game.EventAnswer && message.Data.CorrectIndex != question.CorrectIndex {
+			t.Errorf("expected the spectator to be told the correct answer, got %d", message.Data.CorrectIndex)
+		}
+	}
+	if !seen[game.EventQuestion] || !seen[game.EventAnswer] || !seen[game.EventScores] {
+		t.Errorf("expected the spectator to see the question, answer and scores, got %v", seen)
+	}
+}
+
+func TestQuickMatchOverREST(t *testing.T) {
+	quickMatch := func(name string) joinGameResponse {
+		resp, err := http.Post(testHttpServer.URL+"/game/quickmatch", "application/json", strings.NewReader(fmt.Sprintf(`{"playerName":"%s", "targetSize":2, "questionCount":1, "countdownMs":10, "scoring":"difficulty"}`, name)))
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status OK; got %v", resp.Status)
+		}
+		var response joinGameResponse
+		if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode JSON response: %v", err)
+		}
+		return response
+	}
+	listed := func(lobbyId string) bool {
+		resp, err := http.Get(testHttpServer.URL + "/game/lobbies")
+		if err != nil {
+			t.Fatalf("Failed to make request: %v", err)
+		}
+		defer resp.Body.Close()
+		var response struct {
+			Lobbies []game.LobbySummary `json:"lobbies"`
+		}
+		if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+			t.Fatalf("Failed to decode JSON response: %v", err)
+		}
+		for _, lobby := range response.Lobbies {
+			if lobby.ID == lobbyId {
+				return true
+			}
+		}
+		return false
+	}
+
+	first := quickMatch("Alice")
+	if !listed(first.LobbyId) {
+		t.Errorf("expected the quick match lobby to be listed while it waits for players")
+	}
+	second := quickMatch("Bob")
+	if second.LobbyId != first.LobbyId {
+		t.Fatalf("expected the second player to be matched into the first one's lobby")
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(first.LobbyId)
+	if state := lobby.GameStatus().State; state != game.Starting && state != game.Started {
+		t.Errorf("expected the game to start once the lobby was full, got state %v", state)
+	}
+	if listed(first.LobbyId) {
+		t.Errorf("expected the started lobby to be gone from the list")
+	}
+
+	resp, err := http.Post(testHttpServer.URL+"/game/quickmatch", "application/json", strings.NewReader(`{"targetSize":100}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an oversized quick match to be refused, got %v", resp.Status)
+	}
+}
+
+func TestRematchOverREST(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":0}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	lobby.StartGame(testGameServer.Questions())
+	for lobby.GameStatus().State != game.Started {
+		time.Sleep(time.Millisecond)
+	}
+	question := lobby.Questions[0]
+	postAnswer(t, response.LobbyId, response.SessionId, question.ID, question.CorrectIndex)
+
+	resp, err = http.Post(testHttpServer.URL+"/game/rematch", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s", "sessionId":"%s"}`, response.LobbyId, response.SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+
+	resp, err = http.Get(testHttpServer.URL + "/game/history/" + response.LobbyId)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var history struct {
+		Rounds    []game.RoundResult    `json:"rounds"`
+		Standings []game.SeriesStanding `json:"standings"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if len(history.Rounds) != 1 || len(history.Standings) != 1 || history.Standings[0].Total != 10 {
+		t.Errorf("expected one round with the player on 10 points, got %+v", history)
+	}
+	if lobby.GameStatus().State != game.Waiting {
+		t.Errorf("expected the lobby to be waiting for the next round")
+	}
+}
+
+func TestQuestionBanksOverREST(t *testing.T) {
+	resp, err := http.Get(testHttpServer.URL + "/game/questionbanks")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var listing struct {
+		QuestionBanks []struct {
+			Name      string `json:"name"`
+			Questions int    `json:"questions"`
+			Default   bool   `json:"default"`
+		} `json:"questionBanks"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if len(listing.QuestionBanks) != 2 || listing.QuestionBanks[0].Name != "cap-table" || !listing.QuestionBanks[0].Default || listing.QuestionBanks[1].Name != "easy" {
+		t.Fatalf("expected the cap-table and easy banks, got %+v", listing)
+	}
+
+	resp, err = http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":2, "countdownMs":0, "questionBank":"easy"}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+
+	resp, err = http.Post(testHttpServer.URL+"/game/start", "application/json", strings.NewReader(fmt.Sprintf(`{"lobbyId":"%s","sessionId":"%s"}`, response.LobbyId, response.SessionId)))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status OK; got %v", resp.Status)
+	}
+	easy := make(map[string]bool)
+	easyBank, _ := testGameServer.QuestionBank("easy")
+	for _, question := range easyBank {
+		easy[question.ID+question.QuestionText] = true
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	for lobby.GameStatus().State != game.Started {
+		time.Sleep(time.Millisecond)
+	}
+	for _, question := range lobby.Questions {
+		if !easy[question.ID+question.QuestionText] {
+			t.Errorf("expected only questions from the easy bank, got %+v", question)
+		}
+	}
+	if bank := lobby.Settings().QuestionBank; bank != "easy" {
+		t.Errorf("expected the lobby settings to name the easy bank, got %q", bank)
+	}
+
+	resp, err = http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":2, "questionBank":"nope"}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an unknown question bank to be a bad request, got %v", resp.Status)
+	}
+}
+
+func TestAdminQuestionBankReload(t *testing.T) {
+	resp, err := http.Get(testHttpServer.URL + "/admin/questionbanks/reloads")
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected admin endpoints to be off without an admin token, got %v", resp.Status)
+	}
+
+	testGameServer.AdminToken = "test-admin-token"
+	defer func() { testGameServer.AdminToken = "" }()
+	request, _ := http.NewRequest(http.MethodPost, testHttpServer.URL+"/admin/questionbanks/reload", nil)
+	request.Header.Set("Authorization", "Bearer wrong")
+	resp, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected the wrong admin token to be refused, got %v", resp.Status)
+	}
+
+	request.Header.Set("Authorization", "Bearer test-admin-token")
+	resp, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var reload struct {
+		Results []questionbank.ReloadResult `json:"results"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&reload); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if len(reload.Results) != 2 || !reload.Results[0].Reloaded || !reload.Results[1].Reloaded {
+		t.Errorf("expected both banks to be reloaded, got %+v", reload.Results)
+	}
+	if easyBank, _ := testGameServer.QuestionBank("easy"); len(easyBank) != reload.Results[1].Questions {
+		t.Errorf("expected the easy bank to have the reloaded questions")
+	}
+
+	request, _ = http.NewRequest(http.MethodGet, testHttpServer.URL+"/admin/questionbanks/reloads", nil)
+	request.Header.Set("Authorization", "Bearer test-admin-token")
+	resp, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the reload results, got %v", resp.Status)
+	}
+}
+
+// adminRequest makes a request to an admin endpoint with the test admin token.
+func adminRequest(t *testing.T, method, path, body string) *http.Response {
+	t.Helper()
+	request, _ := http.NewRequest(method, testHttpServer.URL+path, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer test-admin-token")
+	request.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	return resp
+}
+
+func TestAdminQuestionAuthoring(t *testing.T) {
+	testGameServer.AdminToken = "test-admin-token"
+	defer func() { testGameServer.AdminToken = "" }()
+
+	resp := adminRequest(t, http.MethodGet, "/admin/questions?bank=easy", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected authoring to be off without an editor, got %v", resp.Status)
+	}
+
+	editor, err := questionbank.NewEditor(context.Background(), &questionbank.FileRevisionStore{Path: filepath.Join(t.TempDir(), "revisions.jsonl")})
+	if err != nil {
+		t.Fatalf("Failed to make the editor: %v", err)
+	}
+	testGameServer.SetEditor(editor)
+	defer testGameServer.SetEditor(nil)
+	easyBank, _ := testGameServer.QuestionBank("easy")
+
+	resp = adminRequest(t, http.MethodPost, "/admin/questions?bank=easy", `{"author":"writer", "question":{"id":"authored-1", "questionText":"Is this question new?", "options":["Yes","No"], "correctIndex":0}}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status Created; got %v", resp.Status)
+	}
+	resp = adminRequest(t, http.MethodPost, "/admin/questions?bank=easy", `{"question":{"id":"authored-1", "questionText":"Again?", "options":["Yes","No"]}}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected a taken id to conflict, got %v", resp.Status)
+	}
+	options, _ := json.Marshal(easyBank[0].Options)
+	resp = adminRequest(t, http.MethodPut, "/admin/questions/"+easyBank[0].ID+"?bank=easy", fmt.Sprintf(`{"author":"editor", "question":{"questionText":"Reworded?", "options":%s, "correctIndex":5}}`, options))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an out of range correct index to be refused, got %v", resp.Status)
+	}
+	resp = adminRequest(t, http.MethodPut, "/admin/questions/"+easyBank[0].ID+"?bank=easy", `{"author":"editor", "question":{"questionText":"Reworded?", "options":["Yes","No"], "correctIndex":1}}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected changing the options to be refused, got %v", resp.Status)
+	}
+	resp = adminRequest(t, http.MethodPut, "/admin/questions/"+easyBank[0].ID+"?bank=easy", fmt.Sprintf(`{"author":"editor", "question":{"questionText":"Reworded?", "options":%s, "correctIndex":1}}`, options))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", resp.Status)
+	}
+	resp = adminRequest(t, http.MethodPost, "/admin/questions/"+easyBank[1].ID+"/retire?bank=easy", `{"author":"editor"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status OK; got %v", resp.Status)
+	}
+
+	// new lobbies draw from the edited bank straight away.
+	updated, _ := testGameServer.QuestionBank("easy")
+	if len(updated) != len(easyBank) || updated[0].QuestionText != "Reworded?" || updated[len(updated)-1].ID != "authored-1" {
+		t.Errorf("expected the easy bank to have the edits, got %+v", updated)
+	}
+	for _, question := range updated {
+		if question.ID == easyBank[1].ID {
+			t.Errorf("expected the retired question to be left out of the bank")
+		}
+	}
+
+	resp = adminRequest(t, http.MethodGet, "/admin/questions/"+easyBank[0].ID+"/history?bank=easy", "")
+	defer resp.Body.Close()
+	var history struct {
+		Revisions []questionbank.Revision `json:"revisions"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if len(history.Revisions) != 1 || history.Revisions[0].Author != "editor" || history.Revisions[0].Question.QuestionText != "Reworded?" {
+		t.Errorf("expected the update in the history, got %+v", history)
+	}
+
+	resp = adminRequest(t, http.MethodGet, "/admin/questions?bank=easy&retired=true", "")
+	defer resp.Body.Close()
+	var listing struct {
+		Questions []questionbank.AuthoredQuestion `json:"questions"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	if len(listing.Questions) != len(easyBank)+1 || !listing.Questions[1].Retired || listing.Questions[0].Revision != 1 {
+		t.Errorf("expected every question including the retired one, got %+v", listing.Questions)
+	}
+}
+
+func TestQuestionTypesOverREST(t *testing.T) {
+	resp, err := http.Post(testHttpServer.URL+"/game/newlobby", "application/json", strings.NewReader(`{"questionCount":1, "countdownMs":10}`))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	var response joinGameResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode JSON response: %v", err)
+	}
+	lobby, _ := testGameServer.Lobbies.GetLobby(response.LobbyId)
+	question := &game.Question{ID: "caps", QuestionText: "Which are caps?", Type: game.MultiSelect, Options: []string{"Beanie", "Boot", "Beret"}, CorrectIndexes: []int{0, 2}}
+	if err := lobby.StartGame([]*game.Question{question}); err != nil {
+		t.Fatalf("Failed to start game: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	post := func(answer string) (int, server.ErrorCode, bool) {
+		t.Helper()
+		resp, err := http.Post(testHttpServer.URL+"/game/answer", "application/json", strings.NewReader(fmt.Sprintf(`{"sessionId":"%s", "lobbyId":"%s", "questionId":"caps", "answer":%s}`, response.SessionId, response.LobbyId, answer)))
+		if err != nil {
+			t.Fatalf("Failed to submit answer: %v", err)
+		}
+		defer resp.Body.Close()
+		var answerResponse struct {
+			Correct bool             `json:"correct"`
+			Code    server.ErrorCode `json:"code"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&answerResponse); err != nil {
+			t.Fatalf("Failed to decode JSON response: %v", err)
+		}
+		return resp.StatusCode, answerResponse.Code, answerResponse.Correct
+	}
+
+	if status, code, _ := post(`"beanie"`); status != http.StatusBadRequest || code != server.CodeInvalidRequest {
+		t.Errorf("expected an answer that is not an index, true, false, an array or a number to be refused, got %d %q", status, code)
+	}
+	if status, code, _ := post(`[0, 0]`); status != http.StatusBadRequest || code != server.CodeInvalidAnswer {
+		t.Errorf("expected picking an option twice to be refused, got %d %q", status, code)
+	}
+	if status, code, correct := post(`[2, 0]`); status != http.StatusOK || code != "" || !correct {
+		t.Errorf("expected every correct option to be right, got %d %q correct=%v", status, code, correct)
+	}
 }
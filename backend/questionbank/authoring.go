@@ -0,0 +1,345 @@
+package questionbank
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ProlificLabs/captrivia/game"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// questions can be written and edited through the admin api as well as in the bank's files. every edit is kept as a revision,
+// and a bank's questions are whatever its source has with the latest revision of each question laid over the top.
+// a question keeps its id through every edit, and ids are never used again once retired. analytics only record the question id
+// and the index of the option picked, so edits cannot change a question's type or options, which would change what those indexes mean.
+// a question that needs different options is retired and written again under a new id. edits made to the bank's files are not checked.
+
+var (
+	ErrQuestionExists   = errors.New("a question with that id already exists")
+	ErrQuestionNotFound = errors.New("question not found")
+	ErrQuestionRetired  = errors.New("question is retired")
+	ErrInvalidQuestion  = errors.New("invalid question")
+)
+
+// Revision is one edit to a question.
+type Revision struct {
+	Bank       string        `json:"bank"`
+	QuestionID string        `json:"questionId"`
+	Revision   int           `json:"revision"` // 1 for the first edit made through the api, even to a question that came from the bank's source
+	Question   game.Question `json:"question"` // the whole question as of this revision
+	Retired    bool          `json:"retired"`
+	Author     string        `json:"author"`
+	Time       time.Time     `json:"time"`
+}
+
+// RevisionStore is somewhere revisions are kept for good.
+type RevisionStore interface {
+	// Revisions returns every revision ever appended, oldest first.
+	Revisions(ctx context.Context) ([]Revision, error)
+	Append(ctx context.Context, revision Revision) error
+}
+
+// FileRevisionStore keeps revisions in a file, one json object per line, only ever appending to it.
+type FileRevisionStore struct {
+	Path  string
+	mutex sync.Mutex
+}
+
+func (f *FileRevisionStore) Revisions(ctx context.Context) ([]Revision, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	file, err := os.Open(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // nothing has been edited yet
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var revisions []Revision
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var revision Revision
+		if err := json.Unmarshal(scanner.Bytes(), &revision); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", f.Path, line, err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, scanner.Err()
+}
+
+func (f *FileRevisionStore) Append(ctx context.Context, revision Revision) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	line, err := json.Marshal(revision)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	// make sure it is on disk before the edit is reported as done.
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// AuthoredQuestion is a question as it currently stands, for listing.
+type AuthoredQuestion struct {
+	game.Question
+	Revision  int        `json:"revision"` // 0 if it has never been edited through the api
+	Retired   bool       `json:"retired"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Editor makes edits to the banks' questions, keeping every revision in its store.
+type Editor struct {
+	mutex     sync.Mutex
+	store     RevisionStore
+	revisions map[string]map[string][]Revision // bank name to question id to its revisions, oldest first
+	created   map[string][]string              // bank name to the ids of every question with revisions, in the order of their first one
+}
+
+// NewEditor picks up every revision made so far from the store.
+func NewEditor(ctx context.Context, store RevisionStore) (*Editor, error) {
+	revisions, err := store.Revisions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading question revisions: %w", err)
+	}
+	e := &Editor{
+		store:     store,
+		revisions: make(map[string]map[string][]Revision),
+		created:   make(map[string][]string),
+	}
+	for _, revision := range revisions {
+		e.add(revision)
+	}
+	return e, nil
+}
+
+// Apply lays the latest revisions over the questions from a bank's source: edited questions are replaced, retired ones left out
+// and questions created through the api added on the end. the source's questions are not changed.
+func (e *Editor) Apply(bank string, source []*game.Question) []*game.Question {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	questions := make([]*game.Question, 0, len(source)+len(e.created[bank]))
+	for _, question := range e.current(bank, source) {
+		if !question.Retired {
+			q := question.Question
+			questions = append(questions, &q)
+		}
+	}
+	return questions
+}
+
+// List returns the bank's questions as they currently stand, including retired ones if asked for.
+func (e *Editor) List(bank string, source []*game.Question, includeRetired bool) []AuthoredQuestion {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	questions := make([]AuthoredQuestion, 0, len(source))
+	for _, question := range e.current(bank, source) {
+		if includeRetired || !question.Retired {
+			questions = append(questions, question)
+		}
+	}
+	return questions
+}
+
+// History returns every revision of a question, oldest first.
+func (e *Editor) History(bank, questionID string) []Revision {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	history := make([]Revision, len(e.revisions[bank][questionID]))
+	copy(history, e.revisions[bank][questionID])
+	return history
+}
+
+// Create adds a new question to the bank. its id must not have been used in the bank before, even by a retired question.
+// the problems are any warnings about it, like it being nearly the same as another question.
+func (e *Editor) Create(ctx context.Context, bank string, source []*game.Question, question game.Question, author string) (Revision, []Problem, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if strings.TrimSpace(question.ID) == "" {
+		return Revision{}, nil, fmt.Errorf("%w: id is blank", ErrInvalidQuestion)
+	}
+	if _, found := e.find(bank, source, question.ID); found {
+		return Revision{}, nil, ErrQuestionExists
+	}
+	return e.commit(ctx, bank, source, question, false, author)
+}
+
+// Update replaces a question in the bank with a new revision of it. the id, type and options stay the same.
+func (e *Editor) Update(ctx context.Context, bank string, source []*game.Question, question game.Question, author string) (Revision, []Problem, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	existing, found := e.find(bank, source, question.ID)
+	if !found {
+		return Revision{}, nil, ErrQuestionNotFound
+	}
+	if existing.Retired {
+		return Revision{}, nil, ErrQuestionRetired
+	}
+	question.Normalize()
+	if !sameChoices(existing.Question, question) {
+		return Revision{}, nil, fmt.Errorf("%w: its type and options cannot change, retire it and create a new question instead", ErrInvalidQuestion)
+	}
+	return e.commit(ctx, bank, source, question, false, author)
+}
+
+// sameChoices is whether two revisions of a question have the same type and options, in the same order.
+func sameChoices(a, b game.Question) bool {
+	typeOf := func(q game.Question) game.QuestionType {
+		if q.Type == "" {
+			return game.SingleChoice
+		}
+		return q.Type
+	}
+	if typeOf(a) != typeOf(b) || len(a.Options) != len(b.Options) {
+		return false
+	}
+	for i := range a.Options {
+		if a.Options[i] != b.Options[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Retire takes a question out of the bank for good. its revisions are kept, and its id is not used again.
+func (e *Editor) Retire(ctx context.Context, bank string, source []*game.Question, questionID, author string) (Revision, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	existing, found := e.find(bank, source, questionID)
+	if !found {
+		return Revision{}, ErrQuestionNotFound
+	}
+	if existing.Retired {
+		return Revision{}, ErrQuestionRetired
+	}
+	revision, _, err := e.commit(ctx, bank, source, existing.Question, true, author)
+	return revision, err
+}
+
+// commit validates the question alongside the rest of the bank and stores it as the next revision.
+// must be called while holding the editor mutex.
+func (e *Editor) commit(ctx context.Context, bank string, source []*game.Question, question game.Question, retired bool, author string) (Revision, []Problem, error) {
+	question.Normalize()
+	revision := Revision{
+		Bank:       bank,
+		QuestionID: question.ID,
+		Revision:   len(e.revisions[bank][question.ID]) + 1,
+		Question:   question,
+		Retired:    retired,
+		Author:     author,
+		Time:       time.Now(),
+	}
+
+	var warnings []Problem
+	if !retired {
+		// check it against the bank as it would be after the edit, only going by problems with this question,
+		// so that an old problem somewhere else in the bank does not hold it up.
+		location := fmt.Sprintf("bank %s revision %d", bank, revision.Revision)
+		located := []LocatedQuestion{{Question: &question, Location: location}}
+		for _, other := range e.current(bank, source) {
+			if other.ID != question.ID && !other.Retired {
+				q := other.Question
+				located = append(located, LocatedQuestion{Question: &q, Location: "bank " + bank})
+			}
+		}
+		var errs []string
+		for _, problem := range Validate(bank, located) {
+			if problem.Location != location {
+				continue
+			}
+			if problem.Severity == SeverityError {
+				errs = append(errs, problem.Message)
+			} else {
+				warnings = append(warnings, problem)
+			}
+		}
+		if len(errs) > 0 {
+			return Revision{}, nil, fmt.Errorf("%w: %s", ErrInvalidQuestion, strings.Join(errs, ", "))
+		}
+	}
+
+	if err := e.store.Append(ctx, revision); err != nil {
+		return Revision{}, nil, fmt.Errorf("saving the revision: %w", err)
+	}
+	e.add(revision)
+	return revision, warnings, nil
+}
+
+// add puts a revision in its place. must be called while holding the editor mutex, or before anything else can use the editor.
+func (e *Editor) add(revision Revision) {
+	if e.revisions[revision.Bank] == nil {
+		e.revisions[revision.Bank] = make(map[string][]Revision)
+	}
+	if len(e.revisions[revision.Bank][revision.QuestionID]) == 0 {
+		e.created[revision.Bank] = append(e.created[revision.Bank], revision.QuestionID)
+	}
+	e.revisions[revision.Bank][revision.QuestionID] = append(e.revisions[revision.Bank][revision.QuestionID], revision)
+}
+
+// current is every question in the bank as it currently stands, the source's questions first and then the ones created through the api,
+// which are the ones with revisions that are not in the source.
+// must be called while holding the editor mutex.
+func (e *Editor) current(bank string, source []*game.Question) []AuthoredQuestion {
+	revisions := e.revisions[bank]
+	questions := make([]AuthoredQuestion, 0, len(source)+len(e.created[bank]))
+	inSource := make(map[string]bool, len(source))
+	for _, question := range source {
+		inSource[question.ID] = true
+		questions = append(questions, authored(*question, revisions[question.ID]))
+	}
+	for _, id := range e.created[bank] {
+		if !inSource[id] {
+			history := revisions[id]
+			questions = append(questions, authored(history[len(history)-1].Question, history))
+		}
+	}
+	return questions
+}
+
+// find looks a question up in the bank as it currently stands. must be called while holding the editor mutex.
+func (e *Editor) find(bank string, source []*game.Question, questionID string) (AuthoredQuestion, bool) {
+	for _, question := range e.current(bank, source) {
+		if question.ID == questionID {
+			return question, true
+		}
+	}
+	return AuthoredQuestion{}, false
+}
+
+// authored is a question with its latest revision, if it has any, laid over it.
+func authored(question game.Question, history []Revision) AuthoredQuestion {
+	if len(history) == 0 {
+		return AuthoredQuestion{Question: question}
+	}
+	latest := history[len(history)-1]
+	updatedAt := latest.Time
+	return AuthoredQuestion{Question: latest.Question, Revision: latest.Revision, Retired: latest.Retired, UpdatedAt: &updatedAt}
+}
@@ -0,0 +1,101 @@
+package questionbank
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ProlificLabs/captrivia/game"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// postgresPrefix marks a bank location as a postgres table rather than a path, as in "postgres:questions".
+const postgresPrefix = "postgres:"
+
+// Bank is a named set of questions that lobbies can choose to play with.
+type Bank struct {
+	Name      string
+	Source    QuestionSource
+	Questions []*game.Question
+	Located   []LocatedQuestion // the same questions, along with where each one is
+}
+
+// Spec names a bank and says where its questions are, see ParseSpecs.
+type Spec struct {
+	Name     string
+	Location string
+}
+
+// ParseSpecs reads a comma separated list of name=location pairs, like "cap-table=questions.json,easy=easyquestions.json".
+// the first bank listed is the default one.
+func ParseSpecs(specs string) ([]Spec, error) {
+	var parsed []Spec
+	seen := make(map[string]bool)
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, location, ok := strings.Cut(spec, "=")
+		name, location = strings.TrimSpace(name), strings.TrimSpace(location)
+		if !ok || name == "" || location == "" {
+			return nil, fmt.Errorf("invalid question bank %q, expected name=location", spec)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("question bank %q is listed twice", name)
+		}
+		seen[name] = true
+		parsed = append(parsed, Spec{Name: name, Location: location})
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("no question banks given")
+	}
+	return parsed, nil
+}
+
+// NeedsDB is whether any of the banks are kept in postgres.
+func NeedsDB(specs []Spec) bool {
+	for _, spec := range specs {
+		if strings.HasPrefix(spec.Location, postgresPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SourceFor works out the source for a location: "postgres:table" for a postgres table (which needs db),
+// a directory, a .csv file, or otherwise a json file.
+func SourceFor(location string, db *sql.DB) (QuestionSource, error) {
+	if table, ok := strings.CutPrefix(location, postgresPrefix); ok {
+		if db == nil {
+			return nil, fmt.Errorf("question bank %s needs a database, but DB_HOST is not set", location)
+		}
+		return PostgresTable{DB: db, Table: table}, nil
+	}
+	if info, err := os.Stat(location); err == nil && info.IsDir() {
+		return Directory{Path: location}, nil
+	}
+	if strings.EqualFold(filepath.Ext(location), ".csv") {
+		return CSVFile{Path: location}, nil
+	}
+	return JSONFile{Path: location}, nil
+}
+
+// Load loads each of the banks, in the same order as the specs.
+func Load(ctx context.Context, specs []Spec, db *sql.DB) ([]Bank, error) {
+	banks := make([]Bank, 0, len(specs))
+	for _, spec := range specs {
+		source, err := SourceFor(spec.Location, db)
+		if err != nil {
+			return nil, err
+		}
+		located, err := LoadLocated(ctx, source)
+		if err != nil {
+			return nil, fmt.Errorf("loading question bank %s: %w", spec.Name, err)
+		}
+		questions, _ := unlocated(located, nil)
+		banks = append(banks, Bank{Name: spec.Name, Source: source, Questions: questions, Located: located})
+	}
+	return banks, nil
+}
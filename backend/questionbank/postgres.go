@@ -0,0 +1,55 @@
+package questionbank
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ProlificLabs/captrivia/game"
+	"github.com/lib/pq"
+)
+
+// PostgresTable is a postgres table of questions, with the columns
+//
+//	id TEXT, question_text TEXT, options TEXT[], correct_index INTEGER,
+//	category TEXT, tags TEXT[], difficulty INTEGER
+//
+// where category, tags and difficulty may be null. the table only holds single choice questions,
+// the other question types need a json or csv source.
+type PostgresTable struct {
+	DB    *sql.DB
+	Table string
+}
+
+func (p PostgresTable) Describe() string {
+	return "postgres table " + p.Table
+}
+
+func (p PostgresTable) Load(ctx context.Context) ([]*game.Question, error) {
+	return unlocated(p.LoadLocated(ctx))
+}
+
+// LoadLocated locates each question by its row's id, there being no lines to go by.
+func (p PostgresTable) LoadLocated(ctx context.Context) ([]LocatedQuestion, error) {
+	rows, err := p.DB.QueryContext(ctx, fmt.Sprintf(
+		`SELECT id, question_text, options, correct_index, category, tags, difficulty FROM %s ORDER BY id`,
+		pq.QuoteIdentifier(p.Table)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Describe(), err)
+	}
+	defer rows.Close()
+
+	var questions []LocatedQuestion
+	for rows.Next() {
+		question := &game.Question{}
+		var category sql.NullString
+		var difficulty sql.NullInt64
+		if err := rows.Scan(&question.ID, &question.QuestionText, pq.Array(&question.Options), &question.CorrectIndex,
+			&category, pq.Array(&question.Tags), &difficulty); err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Describe(), err)
+		}
+		question.Category = category.String
+		question.Difficulty = int(difficulty.Int64)
+		questions = append(questions, LocatedQuestion{Question: question, Location: fmt.Sprintf("%s id %s", p.Describe(), question.ID)})
+	}
+	return questions, rows.Err()
+}
@@ -0,0 +1,289 @@
+package questionbank
+
+import (
+	"context"
+	"errors"
+	"github.com/ProlificLabs/captrivia/game"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
+
+func TestFileSources(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.json"), `[{"id":"1","questionText":"Two plus two?","options":["3","4"],"correctIndex":1}]`)
+	writeFile(t, filepath.Join(dir, "b.csv"), "questionText,id,options,correctIndex,tags,difficulty\n"+
+		"\"Capital of France?\",2,Paris|Lyon|Nice,0,geography|europe,2\n")
+	writeFile(t, filepath.Join(dir, "notes.txt"), "not questions")
+
+	questions, err := CSVFile{Path: filepath.Join(dir, "b.csv")}.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load the csv: %v", err)
+	}
+	if len(questions) != 1 || questions[0].ID != "2" || len(questions[0].Options) != 3 || questions[0].Tags[1] != "europe" || questions[0].Difficulty != 2 {
+		t.Errorf("csv question not read right, got %+v", questions[0])
+	}
+
+	questions, err = Directory{Path: dir}.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load the directory: %v", err)
+	}
+	if len(questions) != 2 || questions[0].ID != "1" || questions[1].ID != "2" {
+		t.Errorf("expected the json question then the csv one, got %d questions", len(questions))
+	}
+
+	writeFile(t, filepath.Join(dir, "bad.csv"), "id,questionText,options,correctIndex\n3,Broken?,a|b,first\n")
+	_, err = CSVFile{Path: filepath.Join(dir, "bad.csv")}.Load(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "bad.csv:2") {
+		t.Errorf("expected the error to point at line 2 of bad.csv, got %v", err)
+	}
+}
+
+func TestParseSpecs(t *testing.T) {
+	specs, err := ParseSpecs("cap-table=questions.json, easy=easyquestions.json")
+	if err != nil || len(specs) != 2 || specs[1] != (Spec{Name: "easy", Location: "easyquestions.json"}) {
+		t.Errorf("expected two banks, got %+v %v", specs, err)
+	}
+	if NeedsDB(specs) {
+		t.Errorf("expected file banks not to need the database")
+	}
+	if _, err := ParseSpecs("a=x.json,a=y.json"); err == nil {
+		t.Errorf("expected a bank listed twice to be an error")
+	}
+	if _, err := ParseSpecs("just-a-file.json"); err == nil {
+		t.Errorf("expected a bank without a name to be an error")
+	}
+	if _, err := SourceFor("postgres:questions", nil); err == nil {
+		t.Errorf("expected a postgres bank without a database to be an error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.json")
+	writeFile(t, path, `[
+  {"id":"1","questionText":"What is the capital of France?","options":["Paris","Lyon"],"correctIndex":0},
+  {"id":"2","questionText":"What is the capital city of France?","options":["Paris","paris "],"correctIndex":0},
+  {"id":"1","questionText":"How many legs does a spider have?","options":["8"],"correctIndex":1},
+  {"id":"4","questionText":"","options":["a","b"],"correctIndex":0}
+]`)
+	questions, err := JSONFile{Path: path}.LoadLocated(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load the bank: %v", err)
+	}
+	if questions[2].Location != path+":4" {
+		t.Errorf("expected the third question to be on line 4, got %s", questions[2].Location)
+	}
+
+	problems := Validate("test", questions)
+	expected := []struct {
+		severity Severity
+		line     string
+		message  string
+	}{
+		{SeverityError, ":3", "repeats option 0"},
+		{SeverityError, ":4", "duplicate id, already used at " + path + ":2"},
+		{SeverityError, ":4", "has 1 options"},
+		{SeverityError, ":4", "correctIndex 1 is out of range"},
+		{SeverityError, ":5", "question text is blank"},
+		{SeverityWarning, ":3", "nearly the same question as \"1\""},
+	}
+	for _, want := range expected {
+		found := false
+		for _, problem := range problems {
+			if problem.Severity == want.severity && strings.HasSuffix(problem.Location, want.line) && strings.Contains(problem.Message, want.message) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected a %s at line %s saying %q, got %v", want.severity, want.line, want.message, problems)
+		}
+	}
+	if len(problems) != len(expected) {
+		t.Errorf("expected %d problems, got %v", len(expected), problems)
+	}
+	if !Fatal(problems) || Fatal(problems[len(problems)-1:]) {
+		t.Errorf("expected errors to be fatal and warnings not")
+	}
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bank.json")
+	writeFile(t, path, `[{"id":"1","questionText":"Two plus two?","options":["3","4"],"correctIndex":1}]`)
+	banks, err := Load(context.Background(), []Spec{{Name: "test", Location: path}}, nil)
+	if err != nil {
+		t.Fatalf("failed to load the bank: %v", err)
+	}
+	current := banks[0].Questions
+	reloader := NewReloader(banks, func(bank string, questions []*game.Question) { current = questions })
+	if results := reloader.ReloadChanged(context.Background()); len(results) != 0 {
+		t.Errorf("expected nothing to reload before the file changes, got %+v", results)
+	}
+
+	// set the modification time explicitly, the filesystem may not notice a change within the same instant.
+	changed := time.Now().Add(time.Minute)
+	writeFile(t, path, `[{"id":"1","questionText":"Two plus two?","options":["3","4"],"correctIndex":1},
+{"id":"2","questionText":"Three plus three?","options":["6","7"],"correctIndex":0}]`)
+	os.Chtimes(path, changed, changed)
+	results := reloader.ReloadChanged(context.Background())
+	if len(results) != 1 || !results[0].Reloaded || len(current) != 2 {
+		t.Fatalf("expected the bank to be reloaded with 2 questions, got %+v and %d questions", results, len(current))
+	}
+
+	changed = changed.Add(time.Minute)
+	writeFile(t, path, `[{"id":"1","questionText":"Two plus two?","options":["4"],"correctIndex":3}]`)
+	os.Chtimes(path, changed, changed)
+	results = reloader.ReloadChanged(context.Background())
+	if len(results) != 1 || results[0].Reloaded || !Fatal(results[0].Problems) || len(current) != 2 {
+		t.Errorf("expected the broken bank to be refused and the old questions kept, got %+v and %d questions", results, len(current))
+	}
+	if history := reloader.Results(); len(history) != 2 || !history[0].Reloaded {
+		t.Errorf("expected both reloads to be remembered, got %+v", history)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	ctx := context.Background()
+	store := &FileRevisionStore{Path: filepath.Join(t.TempDir(), "revisions.jsonl")}
+	editor, err := NewEditor(ctx, store)
+	if err != nil {
+		t.Fatalf("failed to make the editor: %v", err)
+	}
+	source := []*game.Question{
+		{ID: "1", QuestionText: "Two plus two?", Options: []string{"3", "4"}, CorrectIndex: 1},
+		{ID: "2", QuestionText: "Capital of France?", Options: []string{"Paris", "Lyon"}, CorrectIndex: 0},
+	}
+
+	if _, _, err := editor.Create(ctx, "test", source, *source[0], "writer"); err != ErrQuestionExists {
+		t.Errorf("expected an id from the source to be taken, got %v", err)
+	}
+	if _, _, err := editor.Create(ctx, "test", source, game.Question{ID: "3", QuestionText: "Broken?", Options: []string{"a"}}, "writer"); !errors.Is(err, ErrInvalidQuestion) {
+		t.Errorf("expected a question with one option to be invalid, got %v", err)
+	}
+	if _, _, err := editor.Create(ctx, "test", source, game.Question{ID: "3", QuestionText: "Three plus three?", Options: []string{"6", "7"}}, "writer"); err != nil {
+		t.Fatalf("failed to create a question: %v", err)
+	}
+	revision, _, err := editor.Update(ctx, "test", source, game.Question{ID: "1", QuestionText: "What is two plus two?", Options: []string{"3", "4"}, CorrectIndex: 1}, "editor")
+	if err != nil || revision.Revision != 1 {
+		t.Fatalf("failed to update a question from the source: %v %+v", err, revision)
+	}
+	// answers are recorded by option index, so the options have to stay put.
+	if _, _, err := editor.Update(ctx, "test", source, game.Question{ID: "1", QuestionText: "What is two plus two?", Options: []string{"4", "3"}, CorrectIndex: 0}, "editor"); !errors.Is(err, ErrInvalidQuestion) {
+		t.Errorf("expected reordering the options to be refused, got %v", err)
+	}
+	if _, _, err := editor.Update(ctx, "test", source, game.Question{ID: "1", QuestionText: "What is two plus two?", Type: game.MultiSelect, Options: []string{"3", "4"}, CorrectIndexes: []int{1}}, "editor"); !errors.Is(err, ErrInvalidQuestion) {
+		t.Errorf("expected changing the type to be refused, got %v", err)
+	}
+	if _, err := editor.Retire(ctx, "test", source, "2", "editor"); err != nil {
+		t.Fatalf("failed to retire a question: %v", err)
+	}
+	if _, _, err := editor.Update(ctx, "test", source, *source[1], "editor"); err != ErrQuestionRetired {
+		t.Errorf("expected a retired question not to be updated, got %v", err)
+	}
+	if _, _, err := editor.Create(ctx, "test", source, *source[1], "writer"); err != ErrQuestionExists {
+		t.Errorf("expected a retired question's id not to be used again, got %v", err)
+	}
+
+	check := func(editor *Editor) {
+		t.Helper()
+		questions := editor.Apply("test", source)
+		if len(questions) != 2 || questions[0].QuestionText != "What is two plus two?" || questions[1].ID != "3" {
+			t.Errorf("expected the edited question then the created one, got %+v", questions)
+		}
+		if listed := editor.List("test", source, true); len(listed) != 3 || !listed[1].Retired {
+			t.Errorf("expected the retired question to be listed when asked for, got %+v", listed)
+		}
+		if history := editor.History("test", "2"); len(history) != 1 || !history[0].Retired || history[0].Author != "editor" {
+			t.Errorf("expected the retirement in the history, got %+v", history)
+		}
+	}
+	check(editor)
+	if source[0].QuestionText != "Two plus two?" {
+		t.Errorf("expected the source's questions to be left alone")
+	}
+
+	// everything should come back the same from the store.
+	reopened, err := NewEditor(ctx, store)
+	if err != nil {
+		t.Fatalf("failed to reopen the editor: %v", err)
+	}
+	check(reopened)
+}
+
+func TestQuestionTypes(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "types.json")
+	writeFile(t, jsonPath, `[
+  {"id":"tf","type":"trueFalse","questionText":"Is a beret a cap?","correctIndex":1},
+  {"id":"ms","type":"multiSelect","questionText":"Which are caps?","options":["Beanie","Boot","Beret"],"correctIndexes":[0,2],"credit":"partial"},
+  {"id":"num","type":"numeric","questionText":"How many caps?","numeric":{"value":42,"bands":[{"within":5,"credit":0.5}]}},
+  {"id":"ms2","type":"multiSelect","questionText":"Which are boots?","options":["Beanie","Boot"],"correctIndexes":[1,1,4],"credit":"most"},
+  {"id":"num2","type":"numeric","questionText":"How many boots?","options":["1","2"],"numeric":{"value":2,"rule":"closestWithoutOver","bands":[{"within":1,"credit":2}]}},
+  {"id":"num3","type":"numeric","questionText":"How many gloves?"},
+  {"id":"odd","type":"essay","questionText":"Describe a cap."}
+]`)
+	questions, err := JSONFile{Path: jsonPath}.LoadLocated(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load the bank: %v", err)
+	}
+	if len(questions[0].Options) != 2 {
+		t.Errorf("expected the true/false question to be given its options, got %v", questions[0].Options)
+	}
+
+	problems := Validate("types", questions)
+	expected := []struct {
+		line    string
+		message string
+	}{
+		{":5", "correctIndexes has 1 more than once"},
+		{":5", "correctIndexes has 4, which is out of range"},
+		{":5", "unknown credit rule"},
+		{":6", "numeric questions have no options"},
+		{":6", "tolerance bands only apply"},
+		{":7", "need a numeric answer"},
+		{":8", "unknown question type"},
+	}
+	for _, want := range expected {
+		found := false
+		for _, problem := range problems {
+			if problem.Severity == SeverityError && strings.HasSuffix(problem.Location, want.line) && strings.Contains(problem.Message, want.message) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected an error at line %s saying %q, got %v", want.line, want.message, problems)
+		}
+	}
+	if len(problems) != len(expected) {
+		t.Errorf("expected %d problems, got %v", len(expected), problems)
+	}
+
+	csvPath := filepath.Join(dir, "types.csv")
+	writeFile(t, csvPath, "id,type,questionText,options,correctIndex,correctIndexes,credit,value,rule,bands,unit\n"+
+		"tf,trueFalse,Is a beret a cap?,,0,,,,,,\n"+
+		"ms,multiSelect,Which are caps?,Beanie|Boot|Beret,,0|2,partial,,,,\n"+
+		"num,numeric,How much is a cap?,,,,,19.99,,1:1|5:0.5,USD\n")
+	loaded, err := CSVFile{Path: csvPath}.Load(context.Background())
+	if err != nil {
+		t.Fatalf("failed to load the csv bank: %v", err)
+	}
+	if len(loaded) != 3 || len(loaded[0].Options) != 2 || len(loaded[1].CorrectIndexes) != 2 || loaded[1].Credit != game.CreditPartial ||
+		loaded[2].Numeric == nil || loaded[2].Numeric.Value != 19.99 || len(loaded[2].Numeric.Bands) != 2 || loaded[2].Numeric.Unit != "USD" {
+		t.Errorf("unexpected questions from the csv bank: %+v", loaded)
+	}
+	located := make([]LocatedQuestion, len(loaded))
+	for i, question := range loaded {
+		located[i] = LocatedQuestion{Question: question}
+	}
+	if problems := Validate("csv", located); len(problems) != 0 {
+		t.Errorf("expected the csv questions to be fine, got %v", problems)
+	}
+}
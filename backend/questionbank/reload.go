@@ -0,0 +1,182 @@
+package questionbank
+
+import (
+	"context"
+	"github.com/ProlificLabs/captrivia/game"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxReloadResults is how many of the latest reloads a Reloader remembers.
+const maxReloadResults = 50
+
+// ChangeTracker is a source that can tell when it has changed, so it only needs loading again when it has.
+// the file sources go by modification time. postgres tables cannot, and are only reloaded when asked.
+type ChangeTracker interface {
+	ModTime() (time.Time, error)
+}
+
+func (f JSONFile) ModTime() (time.Time, error) {
+	return modTime(f.Path)
+}
+
+func (f CSVFile) ModTime() (time.Time, error) {
+	return modTime(f.Path)
+}
+
+// ModTime is the latest of the directory's own modification time, which changes when files are added or removed,
+// and that of each file in it.
+func (d Directory) ModTime() (time.Time, error) {
+	latest, err := modTime(d.Path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	entries, err := os.ReadDir(d.Path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since it was listed, the directory's time will have changed for that
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func modTime(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// ReloadResult is how one attempt to reload a bank went.
+// a bank that fails to load or has errors in it is left as it was, and the result says why.
+type ReloadResult struct {
+	Bank      string    `json:"bank"`
+	Source    string    `json:"source"`
+	Time      time.Time `json:"time"`
+	Reloaded  bool      `json:"reloaded"` // whether the bank now has the new questions
+	Questions int       `json:"questions"`
+	Error     string    `json:"error,omitempty"`
+	Problems  []Problem `json:"problems,omitempty"` // validation errors and warnings
+}
+
+// Reloader keeps banks up to date with their sources, loading and validating them again when they change
+// and handing the new questions to swap, which is where the server starts using them.
+type Reloader struct {
+	mutex    sync.Mutex
+	banks    []Bank
+	modTimes map[string]time.Time
+	swap     func(bank string, questions []*game.Question)
+	results  []ReloadResult
+}
+
+// NewReloader watches the banks as they were loaded at startup.
+func NewReloader(banks []Bank, swap func(bank string, questions []*game.Question)) *Reloader {
+	r := &Reloader{
+		banks:    banks,
+		modTimes: make(map[string]time.Time, len(banks)),
+		swap:     swap,
+	}
+	for _, bank := range banks {
+		if tracker, ok := bank.Source.(ChangeTracker); ok {
+			r.modTimes[bank.Name], _ = tracker.ModTime()
+		}
+	}
+	return r
+}
+
+// Start checks the sources for changes every interval, for as long as the server runs.
+func (r *Reloader) Start(interval time.Duration) {
+	log.Printf("watching question banks for changes every %s", interval)
+	go func() {
+		for {
+			time.Sleep(interval)
+			r.ReloadChanged(context.Background())
+		}
+	}()
+}
+
+// ReloadChanged reloads the banks whose sources have changed since they were last loaded, returning how each one went.
+func (r *Reloader) ReloadChanged(ctx context.Context) []ReloadResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	var results []ReloadResult
+	for _, bank := range r.banks {
+		tracker, ok := bank.Source.(ChangeTracker)
+		if !ok {
+			continue
+		}
+		modTime, err := tracker.ModTime()
+		if err == nil && modTime.Equal(r.modTimes[bank.Name]) {
+			continue
+		}
+		// a source that cannot be checked is reported, once per change, by trying to load it.
+		r.modTimes[bank.Name] = modTime
+		results = append(results, r.reload(ctx, bank))
+	}
+	return results
+}
+
+// ReloadAll reloads every bank whether it has changed or not, which is the only way postgres banks get reloaded.
+func (r *Reloader) ReloadAll(ctx context.Context) []ReloadResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	results := make([]ReloadResult, 0, len(r.banks))
+	for _, bank := range r.banks {
+		if tracker, ok := bank.Source.(ChangeTracker); ok {
+			r.modTimes[bank.Name], _ = tracker.ModTime()
+		}
+		results = append(results, r.reload(ctx, bank))
+	}
+	return results
+}
+
+// Results returns the latest reloads, oldest first.
+func (r *Reloader) Results() []ReloadResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	results := make([]ReloadResult, len(r.results))
+	copy(results, r.results)
+	return results
+}
+
+// reload loads and validates one bank, swapping it in if it is fine. must be called while holding the reloader mutex.
+func (r *Reloader) reload(ctx context.Context, bank Bank) ReloadResult {
+	result := ReloadResult{Bank: bank.Name, Source: bank.Source.Describe(), Time: time.Now()}
+	located, err := LoadLocated(ctx, bank.Source)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Questions = len(located)
+		result.Problems = Validate(bank.Name, located)
+		if Fatal(result.Problems) {
+			result.Error = "validation failed, keeping the questions already loaded"
+		} else {
+			questions, _ := unlocated(located, nil)
+			r.swap(bank.Name, questions)
+			result.Reloaded = true
+		}
+	}
+
+	if result.Reloaded {
+		log.Printf("reloaded %d questions into question bank %s from %s", result.Questions, bank.Name, result.Source)
+	} else {
+		log.Printf("failed to reload question bank %s from %s: %s", bank.Name, result.Source, result.Error)
+	}
+	r.results = append(r.results, result)
+	if len(r.results) > maxReloadResults {
+		r.results = r.results[len(r.results)-maxReloadResults:]
+	}
+	return result
+}
@@ -0,0 +1,291 @@
+package questionbank
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/ProlificLabs/captrivia/game"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QuestionSource is somewhere a bank of questions can be loaded from.
+type QuestionSource interface {
+	// Describe says where the questions come from, for logs and error messages.
+	Describe() string
+	Load(ctx context.Context) ([]*game.Question, error)
+}
+
+// LocatedQuestion is a question along with where it came from, like "questions.json:12", so that problems with it can be tracked down.
+type LocatedQuestion struct {
+	*game.Question
+	Location string
+}
+
+// LocatingSource is a source that can say where each of its questions is. all the sources in this package are.
+type LocatingSource interface {
+	QuestionSource
+	LoadLocated(ctx context.Context) ([]LocatedQuestion, error)
+}
+
+// LoadLocated loads the questions from the source along with where each one is.
+// sources that cannot say are located by their description and the question's position in it.
+func LoadLocated(ctx context.Context, source QuestionSource) ([]LocatedQuestion, error) {
+	if locating, ok := source.(LocatingSource); ok {
+		return locating.LoadLocated(ctx)
+	}
+	questions, err := source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	located := make([]LocatedQuestion, 0, len(questions))
+	for i, question := range questions {
+		located = append(located, LocatedQuestion{Question: question, Location: fmt.Sprintf("%s #%d", source.Describe(), i+1)})
+	}
+	return located, nil
+}
+
+// unlocated strips the locations back off.
+func unlocated(located []LocatedQuestion, err error) ([]*game.Question, error) {
+	if err != nil {
+		return nil, err
+	}
+	questions := make([]*game.Question, 0, len(located))
+	for _, question := range located {
+		questions = append(questions, question.Question)
+	}
+	return questions, nil
+}
+
+// JSONFile is a file holding a json array of questions, like questions.json.
+type JSONFile struct {
+	Path string
+}
+
+func (f JSONFile) Describe() string {
+	return f.Path
+}
+
+func (f JSONFile) Load(ctx context.Context) ([]*game.Question, error) {
+	return unlocated(f.LoadLocated(ctx))
+}
+
+// LoadLocated decodes the array one question at a time, so that each one's line in the file is known.
+func (f JSONFile) LoadLocated(ctx context.Context) ([]LocatedQuestion, error) {
+	fileBytes, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(fileBytes))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, fmt.Errorf("%s: expected a json array of questions", f.Path)
+	}
+	var questions []LocatedQuestion
+	for decoder.More() {
+		line := lineAt(fileBytes, decoder.InputOffset())
+		question := &game.Question{}
+		if err := decoder.Decode(question); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", f.Path, line, err)
+		}
+		question.Normalize()
+		questions = append(questions, LocatedQuestion{Question: question, Location: fmt.Sprintf("%s:%d", f.Path, line)})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return questions, nil
+}
+
+// lineAt is the line of the next value after the offset, skipping the whitespace and comma between array elements.
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n,", rune(data[offset])) {
+		offset++
+	}
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+// CSVFile is a csv file with a header row naming its columns, one question per row after that.
+// the columns are id, questionText, options, correctIndex, category, tags and difficulty, in any order,
+// with the options and tags separated by | within their cells. category, tags and difficulty can be left out.
+// questions other than single choice ones say so in a type column, and use these columns as they need:
+// correctIndexes (separated by |) and credit for multi-select, and value, rule, bands and unit for numeric, see numericAnswer.
+type CSVFile struct {
+	Path string
+}
+
+// csvListSeparator splits the options and tags cells of a csv file.
+const csvListSeparator = "|"
+
+func (f CSVFile) Describe() string {
+	return f.Path
+}
+
+func (f CSVFile) Load(ctx context.Context) ([]*game.Question, error) {
+	return unlocated(f.LoadLocated(ctx))
+}
+
+func (f CSVFile) LoadLocated(ctx context.Context) ([]LocatedQuestion, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading header: %w", f.Path, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"id", "questiontext", "options", "correctindex"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%s: missing column %q", f.Path, required)
+		}
+	}
+	cell := func(record []string, column string) string {
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var questions []LocatedQuestion
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		line, _ := reader.FieldPos(0)
+		question := &game.Question{
+			ID:           cell(record, "id"),
+			QuestionText: cell(record, "questiontext"),
+			Type:         game.QuestionType(cell(record, "type")),
+			Options:      splitList(cell(record, "options")),
+			Credit:       game.CreditRule(cell(record, "credit")),
+			Category:     cell(record, "category"),
+			Tags:         splitList(cell(record, "tags")),
+		}
+		// multi-select and numeric questions have no use for a correctIndex, so they can leave it blank.
+		if correctIndex := cell(record, "correctindex"); correctIndex != "" || question.Type == "" ||
+			question.Type == game.SingleChoice || question.Type == game.TrueFalse {
+			if question.CorrectIndex, err = strconv.Atoi(correctIndex); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid correctIndex: %w", f.Path, line, err)
+			}
+		}
+		for _, index := range splitList(cell(record, "correctindexes")) {
+			correctIndex, err := strconv.Atoi(index)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid correctIndexes: %w", f.Path, line, err)
+			}
+			question.CorrectIndexes = append(question.CorrectIndexes, correctIndex)
+		}
+		if question.Type == game.Numeric {
+			if question.Numeric, err = numericAnswer(record, cell); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", f.Path, line, err)
+			}
+		}
+		if difficulty := cell(record, "difficulty"); difficulty != "" {
+			if question.Difficulty, err = strconv.Atoi(difficulty); err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid difficulty: %w", f.Path, line, err)
+			}
+		}
+		question.Normalize()
+		questions = append(questions, LocatedQuestion{Question: question, Location: fmt.Sprintf("%s:%d", f.Path, line)})
+	}
+	return questions, nil
+}
+
+// numericAnswer reads the answer to a numeric question from the value, rule, bands and unit columns.
+// bands are written within:credit, like "1000:1|5000:0.5".
+func numericAnswer(record []string, cell func([]string, string) string) (*game.NumericAnswer, error) {
+	answer := &game.NumericAnswer{Rule: game.NumericRule(cell(record, "rule")), Unit: cell(record, "unit")}
+	var err error
+	if answer.Value, err = strconv.ParseFloat(cell(record, "value"), 64); err != nil {
+		return nil, fmt.Errorf("invalid value: %w", err)
+	}
+	for _, band := range splitList(cell(record, "bands")) {
+		within, credit, ok := strings.Cut(band, ":")
+		parsed := game.ToleranceBand{}
+		if ok {
+			parsed.Within, err = strconv.ParseFloat(strings.TrimSpace(within), 64)
+		}
+		if ok && err == nil {
+			parsed.Credit, err = strconv.ParseFloat(strings.TrimSpace(credit), 64)
+		}
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid band %q, expected within:credit", band)
+		}
+		answer.Bands = append(answer.Bands, parsed)
+	}
+	return answer, nil
+}
+
+func splitList(cell string) []string {
+	if cell == "" {
+		return nil
+	}
+	items := strings.Split(cell, csvListSeparator)
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// Directory is every .json and .csv file in a directory (not its subdirectories), taken in filename order.
+type Directory struct {
+	Path string
+}
+
+func (d Directory) Describe() string {
+	return d.Path
+}
+
+func (d Directory) Load(ctx context.Context) ([]*game.Question, error) {
+	return unlocated(d.LoadLocated(ctx))
+}
+
+func (d Directory) LoadLocated(ctx context.Context) ([]LocatedQuestion, error) {
+	entries, err := os.ReadDir(d.Path)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var questions []LocatedQuestion
+	for _, name := range names {
+		var source LocatingSource
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".json":
+			source = JSONFile{Path: filepath.Join(d.Path, name)}
+		case ".csv":
+			source = CSVFile{Path: filepath.Join(d.Path, name)}
+		default:
+			continue
+		}
+		loaded, err := source.LoadLocated(ctx)
+		if err != nil {
+			return nil, err
+		}
+		questions = append(questions, loaded...)
+	}
+	return questions, nil
+}
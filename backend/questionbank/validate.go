@@ -0,0 +1,136 @@
+package questionbank
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Severity says whether a problem with a bank stops the server from starting.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // the game cannot work properly with the question as it is
+	SeverityWarning Severity = "warning" // worth a look, but the game will cope
+)
+
+// nearDuplicateSimilarity is how much of their wording two questions have to share to be reported as near duplicates.
+const nearDuplicateSimilarity = 0.8
+
+// Problem is something wrong with one of a bank's questions.
+type Problem struct {
+	Severity   Severity `json:"severity"`
+	Bank       string   `json:"bank"`
+	Location   string   `json:"location"` // where the question is, like "questions.json:12"
+	QuestionID string   `json:"questionId"`
+	Message    string   `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: bank %s, question %q: %s", p.Location, p.Severity, p.Bank, p.QuestionID, p.Message)
+}
+
+// Fatal is whether any of the problems is an error, which the server should refuse to start with.
+func Fatal(problems []Problem) bool {
+	for _, problem := range problems {
+		if problem.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateBanks checks every bank, see Validate.
+func ValidateBanks(banks []Bank) []Problem {
+	var problems []Problem
+	for _, bank := range banks {
+		problems = append(problems, Validate(bank.Name, bank.Located)...)
+	}
+	return problems
+}
+
+// Validate checks a bank's questions for anything that would trip the game up: duplicate ids (answers are checked against the id)
+// and anything game.Question.Check finds, like correct indexes that do not point at an option, fewer than two options,
+// blank text, options that repeat, or a numeric question without its answer. those are all errors.
+// questions worded the same or nearly the same as another are only warnings.
+func Validate(bank string, questions []LocatedQuestion) []Problem {
+	var problems []Problem
+	report := func(severity Severity, question LocatedQuestion, format string, args ...interface{}) {
+		problems = append(problems, Problem{
+			Severity:   severity,
+			Bank:       bank,
+			Location:   question.Location,
+			QuestionID: question.ID,
+			Message:    fmt.Sprintf(format, args...),
+		})
+	}
+
+	firstWithID := make(map[string]LocatedQuestion, len(questions))
+	for _, question := range questions {
+		if strings.TrimSpace(question.ID) == "" {
+			report(SeverityError, question, "id is blank")
+		} else if first, ok := firstWithID[question.ID]; ok {
+			report(SeverityError, question, "duplicate id, already used at %s", first.Location)
+		} else {
+			firstWithID[question.ID] = question
+		}
+
+		for _, message := range question.Check() {
+			report(SeverityError, question, "%s", message)
+		}
+	}
+
+	// compare every pair of questions by their wording. banks run to hundreds of questions, not millions, so this is quick enough.
+	words := make([]map[string]bool, len(questions))
+	for i, question := range questions {
+		words[i] = wordSet(question.QuestionText)
+	}
+	for i, question := range questions {
+		for j := 0; j < i; j++ {
+			if len(words[i]) == 0 {
+				break
+			}
+			similarity := jaccard(words[i], words[j])
+			switch {
+			case normalize(question.QuestionText) == normalize(questions[j].QuestionText):
+				report(SeverityWarning, question, "same question as %q at %s", questions[j].ID, questions[j].Location)
+			case similarity >= nearDuplicateSimilarity:
+				report(SeverityWarning, question, "nearly the same question as %q at %s (%.0f%% of the words in common)",
+					questions[j].ID, questions[j].Location, similarity*100)
+			default:
+				continue
+			}
+			break // one match is enough to point it out
+		}
+	}
+	return problems
+}
+
+// normalize lowercases text and reduces it to its words, so that differences in case, spacing and punctuation do not count.
+func normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+func wordSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(normalize(text)) {
+		set[word] = true
+	}
+	return set
+}
+
+// jaccard is how many words two sets have in common, out of all the words in either.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for word := range a {
+		if b[word] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+)
+
+// RequireAdmin guards the admin endpoints, which need the admin token as a bearer token.
+// with no admin token set they are turned off altogether.
+func (gs *GameServer) RequireAdmin(c *gin.Context) {
+	if gs.AdminToken == "" {
+		respondInvalid(c, http.StatusServiceUnavailable, CodeAdminDisabled, "Admin endpoints are not enabled")
+		c.Abort()
+		return
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(gs.AdminToken)) != 1 {
+		respondInvalid(c, http.StatusUnauthorized, CodeUnauthorized, "Missing or wrong admin token")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// ReloadResultsHandler shows how the latest question bank reloads went, including any validation problems that stopped them.
+func (gs *GameServer) ReloadResultsHandler(c *gin.Context) {
+	if gs.Reloader == nil {
+		respondInvalid(c, http.StatusServiceUnavailable, CodeReloadDisabled, "Question banks are not being reloaded")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": gs.Reloader.Results()})
+}
+
+// ReloadHandler reloads every question bank now, without waiting for a change to be noticed.
+// postgres banks have no way to notice changes, so this is how they get reloaded.
+func (gs *GameServer) ReloadHandler(c *gin.Context) {
+	if gs.Reloader == nil {
+		respondInvalid(c, http.StatusServiceUnavailable, CodeReloadDisabled, "Question banks are not being reloaded")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"results": gs.Reloader.ReloadAll(c.Request.Context())})
+}
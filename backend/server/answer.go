@@ -1,50 +1,44 @@
 package server
 
 import (
+	"github.com/ProlificLabs/captrivia/game"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
 )
 
+type answerResponse struct {
+	game.AnswerResult
+	Error string    `json:"error,omitempty"`
+	Code  ErrorCode `json:"code,omitempty"`
+}
+
 func (gs *GameServer) AnswerHandler(c *gin.Context) {
 	var submittedAnswer struct {
-		SessionID  string `json:"sessionId"`
-		QuestionID string `json:"questionId"`
-		LobbyId    string `json:"lobbyId"`
-		Answer     int    `json:"answer"`
+		SessionID  string      `json:"sessionId"`
+		QuestionID string      `json:"questionId"`
+		LobbyId    string      `json:"lobbyId"`
+		Answer     game.Answer `json:"answer"` // an option index, true or false, an array of option indexes or a number, depending on the question type
 	}
 	if err := c.ShouldBindJSON(&submittedAnswer); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
 		return
 	}
 
 	lobby, found := gs.Lobbies.GetLobby(submittedAnswer.LobbyId)
 	if !found {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to find lobby: " + submittedAnswer.LobbyId})
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Failed to find lobby: "+submittedAnswer.LobbyId)
 		return
 	}
 
-	err, points := lobby.SubmitAnswer(submittedAnswer.SessionID, submittedAnswer.QuestionID, submittedAnswer.Answer)
-	//the errors here can all be treated as non-errors, the important part is whether any points was awarded. we could maybe get more info and track a score but the server is going to keep track and push updates to the client so, not worrying about it here.
+	err, result := lobby.Submit(submittedAnswer.SessionID, submittedAnswer.QuestionID, submittedAnswer.Answer)
+	// a wrong answer is still a successful request, the result says what it cost and whether the player can have another go.
+	response := answerResponse{AnswerResult: result}
+	status := http.StatusOK
 	if err != nil {
-		log.Printf("sumbissionError: %s", err.Error())
-		c.JSON(http.StatusOK, gin.H{
-			"submissionError": err.Error(),
-		})
-		return
+		log.Printf("submission error: %s", err.Error())
+		response.Error = err.Error()
+		response.Code, status = errorCode(err)
 	}
-	player, err := lobby.GetPlayer(submittedAnswer.SessionID)
-	if err != nil {
-		log.Printf("sumbissionError %s", err.Error())
-		c.JSON(http.StatusOK, gin.H{
-			"submissionError": err.Error(),
-		})
-		return
-	}
-
-	log.Printf("respond with points %d", points)
-	c.JSON(http.StatusOK, gin.H{
-		"points": points,
-		"score":  player.Score,
-	})
+	c.JSON(status, response)
 }
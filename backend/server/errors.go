@@ -0,0 +1,150 @@
+package server
+
+import (
+	"errors"
+	"github.com/ProlificLabs/captrivia/game"
+	"github.com/ProlificLabs/captrivia/questionbank"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ErrorCode is a stable, machine readable name for why a request failed, sent as "code" next to the human readable "error".
+// bots and clients should go by the code, the wording of the message may change.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest      ErrorCode = "invalid_request"
+	CodeLobbyNotFound       ErrorCode = "lobby_not_found"
+	CodePlayerNotFound      ErrorCode = "player_not_found"
+	CodePlayerAlreadyAdded  ErrorCode = "player_already_added"
+	CodePlayerNameTaken     ErrorCode = "player_name_taken"
+	CodeInvalidPlayerName   ErrorCode = "invalid_player_name"
+	CodeInvalidChat         ErrorCode = "invalid_chat"
+	CodeLobbyNotWaiting     ErrorCode = "lobby_not_waiting"
+	CodeGameNotStarted      ErrorCode = "game_not_started"
+	CodeGameEnded           ErrorCode = "game_ended"
+	CodeGameAlreadyStarted  ErrorCode = "game_already_started"
+	CodeGameNotEnded        ErrorCode = "game_not_ended"
+	CodeWrongQuestion       ErrorCode = "wrong_question"
+	CodeAlreadyAnswered     ErrorCode = "already_answered"
+	CodeIncorrectAnswer     ErrorCode = "incorrect_answer"
+	CodeAnswerCooldown      ErrorCode = "answer_cooldown"
+	CodeInvalidAnswer       ErrorCode = "invalid_answer"
+	CodeNotEnoughQuestions  ErrorCode = "not_enough_questions"
+	CodePlayersNotReady     ErrorCode = "players_not_ready"
+	CodeNotHost             ErrorCode = "not_host"
+	CodeLobbyFull           ErrorCode = "lobby_full"
+	CodeWrongPassword       ErrorCode = "wrong_password"
+	CodeSpectator           ErrorCode = "spectator"
+	CodeAnalyticsDisabled   ErrorCode = "analytics_disabled"
+	CodeAdminDisabled       ErrorCode = "admin_disabled"
+	CodeUnauthorized        ErrorCode = "unauthorized"
+	CodeReloadDisabled      ErrorCode = "reload_disabled"
+	CodeAuthoringDisabled   ErrorCode = "authoring_disabled"
+	CodeQuestionNotFound    ErrorCode = "question_not_found"
+	CodeQuestionExists      ErrorCode = "question_exists"
+	CodeQuestionRetired     ErrorCode = "question_retired"
+	CodeInvalidQuestion     ErrorCode = "invalid_question"
+	CodeUnsupportedProtocol ErrorCode = "unsupported_protocol"
+	CodeInternal            ErrorCode = "internal_error"
+)
+
+// ErrorCodes lists every code, for anything that needs to enumerate them (like the schema check in the tests).
+var ErrorCodes = []ErrorCode{
+	CodeInvalidRequest,
+	CodeLobbyNotFound,
+	CodePlayerNotFound,
+	CodePlayerAlreadyAdded,
+	CodePlayerNameTaken,
+	CodeInvalidPlayerName,
+	CodeInvalidChat,
+	CodeLobbyNotWaiting,
+	CodeGameNotStarted,
+	CodeGameEnded,
+	CodeGameAlreadyStarted,
+	CodeGameNotEnded,
+	CodeWrongQuestion,
+	CodeAlreadyAnswered,
+	CodeIncorrectAnswer,
+	CodeAnswerCooldown,
+	CodeInvalidAnswer,
+	CodeNotEnoughQuestions,
+	CodePlayersNotReady,
+	CodeNotHost,
+	CodeLobbyFull,
+	CodeWrongPassword,
+	CodeSpectator,
+	CodeAnalyticsDisabled,
+	CodeAdminDisabled,
+	CodeUnauthorized,
+	CodeReloadDisabled,
+	CodeAuthoringDisabled,
+	CodeQuestionNotFound,
+	CodeQuestionExists,
+	CodeQuestionRetired,
+	CodeInvalidQuestion,
+	CodeUnsupportedProtocol,
+	CodeInternal,
+}
+
+var (
+	errUnsupportedProtocol = errors.New("unsupported protocol version")
+	errInvalidCommand      = errors.New("invalid command")
+)
+
+// errorCodes is how each of the game's errors, and the question editor's, is reported over http.
+// an incorrect answer is a perfectly good request, so it comes back as a 200 with the answer result.
+var errorCodes = []struct {
+	err    error
+	code   ErrorCode
+	status int
+}{
+	{game.ErrPlayerNotFound, CodePlayerNotFound, http.StatusNotFound},
+	{game.ErrPlayerAlreadyAdded, CodePlayerAlreadyAdded, http.StatusConflict},
+	{game.ErrPlayerNameTaken, CodePlayerNameTaken, http.StatusConflict},
+	{game.ErrInvalidPlayerName, CodeInvalidPlayerName, http.StatusBadRequest},
+	{game.ErrInvalidChat, CodeInvalidChat, http.StatusBadRequest},
+	{game.ErrLobbyNotWaiting, CodeLobbyNotWaiting, http.StatusConflict},
+	{game.ErrGameNotStarted, CodeGameNotStarted, http.StatusConflict},
+	{game.ErrGameEnded, CodeGameEnded, http.StatusConflict},
+	{game.ErrGameAlreadyStarted, CodeGameAlreadyStarted, http.StatusConflict},
+	{game.ErrGameNotEnded, CodeGameNotEnded, http.StatusConflict},
+	{game.ErrWrongQuestion, CodeWrongQuestion, http.StatusConflict},
+	{game.ErrAlreadyAnswered, CodeAlreadyAnswered, http.StatusConflict},
+	{game.ErrIncorrectAnswer, CodeIncorrectAnswer, http.StatusOK},
+	{game.ErrAnswerCooldown, CodeAnswerCooldown, http.StatusTooManyRequests},
+	{game.ErrInvalidAnswer, CodeInvalidAnswer, http.StatusBadRequest},
+	{game.ErrNotEnoughQuestions, CodeNotEnoughQuestions, http.StatusUnprocessableEntity},
+	{game.ErrPlayersNotReady, CodePlayersNotReady, http.StatusConflict},
+	{game.ErrNotHost, CodeNotHost, http.StatusForbidden},
+	{game.ErrLobbyFull, CodeLobbyFull, http.StatusConflict},
+	{game.ErrWrongPassword, CodeWrongPassword, http.StatusForbidden},
+	{game.ErrSpectator, CodeSpectator, http.StatusForbidden},
+	{questionbank.ErrQuestionNotFound, CodeQuestionNotFound, http.StatusNotFound},
+	{questionbank.ErrQuestionExists, CodeQuestionExists, http.StatusConflict},
+	{questionbank.ErrQuestionRetired, CodeQuestionRetired, http.StatusConflict},
+	{questionbank.ErrInvalidQuestion, CodeInvalidQuestion, http.StatusBadRequest},
+	{errUnsupportedProtocol, CodeUnsupportedProtocol, http.StatusBadRequest},
+	{errInvalidCommand, CodeInvalidRequest, http.StatusBadRequest},
+}
+
+// errorCode works out the code and http status for an error from the game package, anything unexpected being an internal error.
+func errorCode(err error) (ErrorCode, int) {
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code, known.status
+		}
+	}
+	return CodeInternal, http.StatusInternalServerError
+}
+
+// respondError reports a failed request, with message saying what was being attempted when err happened.
+func respondError(c *gin.Context, message string, err error) {
+	code, status := errorCode(err)
+	c.JSON(status, gin.H{"error": message + ": " + err.Error(), "code": code})
+}
+
+// respondInvalid reports a request that was refused before it got anywhere near a lobby.
+func respondInvalid(c *gin.Context, status int, code ErrorCode, message string) {
+	c.JSON(status, gin.H{"error": message, "code": code})
+}
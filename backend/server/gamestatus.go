@@ -8,14 +8,14 @@ import (
 func (gs *GameServer) GameStatusHandler(c *gin.Context) {
 	lobbyId := c.Param("lobbyId")
 	if lobbyId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing lobby ID"})
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Missing lobby ID")
 		return
 	}
 
 	// Assuming you have a method GetLobby that retrieves a lobby by its ID
 	lobby, exists := gs.Lobbies.GetLobby(lobbyId)
 	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lobby ID"})
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Invalid lobby ID")
 		return
 	}
 
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/ProlificLabs/captrivia/game"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// hostParams identify the lobby and the session of the host acting on it, and for kicking and transferring host, which player to do it to.
+type hostParams struct {
+	LobbyId   string `json:"lobbyId"`
+	SessionId string `json:"sessionId"`
+	PlayerId  string `json:"playerId"` // public id of the player being acted on
+}
+
+// KickHandler lets the host remove a player from the lobby.
+func (gs *GameServer) KickHandler(c *gin.Context) {
+	var params hostParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	lobby, found := gs.Lobbies.GetLobby(params.LobbyId)
+	if !found {
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby not found")
+		return
+	}
+
+	if err := lobby.KickPlayer(params.SessionId, params.PlayerId); err != nil {
+		respondError(c, "Failed to kick player", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Kicked player successfully", "playerId": params.PlayerId})
+}
+
+// TransferHostHandler lets the host hand the role over to another player.
+func (gs *GameServer) TransferHostHandler(c *gin.Context) {
+	var params hostParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	lobby, found := gs.Lobbies.GetLobby(params.LobbyId)
+	if !found {
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby not found")
+		return
+	}
+
+	if err := lobby.TransferHost(params.SessionId, params.PlayerId); err != nil {
+		respondError(c, "Failed to transfer host", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Transferred host successfully", "hostId": params.PlayerId})
+}
+
+// SettingsHandler lets the host change the lobby's settings while it is waiting for the game to start.
+// the body takes the same settings as creating a lobby. only the ones it has are changed, the rest stay as they were,
+// so taking off a password means sending a blank one.
+func (gs *GameServer) SettingsHandler(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	var settingsParams struct {
+		LobbyId   string `json:"lobbyId"`
+		SessionId string `json:"sessionId"`
+		lobbySettingsParams
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if err := json.Unmarshal(body, &settingsParams); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	lobby, found := gs.Lobbies.GetLobby(settingsParams.LobbyId)
+	if !found {
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby not found")
+		return
+	}
+
+	// start from what the lobby has now, so that decoding the body over it only changes what the host sent.
+	settingsParams.lobbySettingsParams = currentSettingsParams(lobby.Settings())
+	if _, ok := fields["difficultyMix"]; ok {
+		settingsParams.DifficultyMix = nil // maps are merged into when decoding rather than replaced
+	}
+	if err := json.Unmarshal(body, &settingsParams); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	settings, ok := gs.lobbySettings(c, settingsParams.lobbySettingsParams, "Failed to change settings")
+	if !ok {
+		return
+	}
+	if err := lobby.UpdateSettings(settingsParams.SessionId, settings); err != nil {
+		respondError(c, "Failed to change settings", err)
+		return
+	}
+	c.JSON(http.StatusOK, settings.Summary())
+}
+
+// currentSettingsParams turns a lobby's settings back into the parameters they would have been made from.
+func currentSettingsParams(settings game.LobbySettings) lobbySettingsParams {
+	summary := settings.Summary()
+	params := lobbySettingsParams{
+		QuestionCount:  settings.QuestionCount,
+		CountdownMs:    settings.Countdown,
+		TimeoutMs:      settings.QuestionTimeout,
+		Scoring:        summary.Scoring,
+		RoundMode:      string(summary.RoundMode),
+		Password:       settings.Password,
+		QuestionBank:   settings.QuestionBank,
+		QuestionFilter: settings.QuestionFilter,
+		AnswerRules:    settings.AnswerRules,
+		ReadyCheck:     settings.ReadyCheck,
+		JoinRules:      settings.JoinRules,
+	}
+	// the lobby's own slices and map must not be decoded into.
+	params.Categories = append([]string(nil), settings.QuestionFilter.Categories...)
+	params.Tags = append([]string(nil), settings.QuestionFilter.Tags...)
+	if settings.QuestionFilter.DifficultyMix != nil {
+		params.DifficultyMix = make(map[int]int, len(settings.QuestionFilter.DifficultyMix))
+		for difficulty, count := range settings.QuestionFilter.DifficultyMix {
+			params.DifficultyMix[difficulty] = count
+		}
+	}
+	return params
+}
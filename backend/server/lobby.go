@@ -1,55 +1,187 @@
 package server
 
 import (
+	"errors"
 	"github.com/ProlificLabs/captrivia/game"
 	"github.com/gin-gonic/gin"
 	"log"
 	"net/http"
 )
 
+// lobbySettingsParams are the settings a lobby is created with, which the host can also change later, see SettingsHandler.
+type lobbySettingsParams struct {
+	QuestionCount int    `json:"questionCount"`
+	CountdownMs   int    `json:"countdownMs"`
+	TimeoutMs     int    `json:"questionTimeoutMs"` // 0 or absent means questions never time out.
+	Scoring       string `json:"scoring"`           // one of game.ScoringStrategyNames, flat if absent
+	RoundMode     string `json:"roundMode"`         // race if absent
+	Password      string `json:"password"`          // players joining have to give it, if set
+	QuestionBank  string `json:"questionBank"`      // name of the bank to draw questions from, the default bank if absent
+	game.QuestionFilter
+	game.AnswerRules
+	game.ReadyCheck
+	game.JoinRules
+}
+
+// lobbySettings checks over the requested settings and turns them into what the lobby needs.
+// if there is something wrong with them it has already responded saying so, with failure saying what was being attempted.
+func (gs *GameServer) lobbySettings(c *gin.Context, params lobbySettingsParams, failure string) (game.LobbySettings, bool) {
+	if params.QuestionCount < 0 || params.CountdownMs < 0 || params.TimeoutMs < 0 {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid settings: questionCount, countdownMs and questionTimeoutMs cannot be negative")
+		return game.LobbySettings{}, false
+	}
+	if params.QuestionBank == "" {
+		params.QuestionBank = gs.DefaultBank
+	}
+	pool, ok := gs.bankPool(params.QuestionBank)
+	if !ok {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid question bank: there is no bank named "+params.QuestionBank)
+		return game.LobbySettings{}, false
+	}
+
+	// try the filter against the pool now so that a lobby that could never start is not set up that way in the first place.
+	if _, err := params.QuestionFilter.Select(pool(), params.QuestionCount); errors.Is(err, game.ErrNotEnoughQuestions) {
+		respondError(c, failure, err)
+		return game.LobbySettings{}, false
+	} else if err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid question filter: "+err.Error())
+		return game.LobbySettings{}, false
+	}
+
+	scoring, err := game.ScoringStrategyByName(params.Scoring)
+	if err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid scoring: "+err.Error())
+		return game.LobbySettings{}, false
+	}
+
+	roundMode, err := game.ParseRoundMode(params.RoundMode)
+	if err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid round mode: "+err.Error())
+		return game.LobbySettings{}, false
+	}
+
+	if err := params.AnswerRules.Validate(roundMode); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid answer rules: "+err.Error())
+		return game.LobbySettings{}, false
+	}
+
+	if err := params.ReadyCheck.Validate(params.JoinRules); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid ready check: "+err.Error())
+		return game.LobbySettings{}, false
+	}
+
+	if err := params.JoinRules.Validate(); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid join rules: "+err.Error())
+		return game.LobbySettings{}, false
+	}
+
+	return game.LobbySettings{
+		QuestionCount:   params.QuestionCount,
+		Countdown:       params.CountdownMs,
+		QuestionTimeout: params.TimeoutMs,
+		LobbyOptions: game.LobbyOptions{
+			QuestionFilter: params.QuestionFilter,
+			Scoring:        scoring,
+			RoundMode:      roundMode,
+			AnswerRules:    params.AnswerRules,
+			ReadyCheck:     params.ReadyCheck,
+			JoinRules:      params.JoinRules,
+			Password:       params.Password,
+			QuestionBank:   params.QuestionBank,
+			QuestionPool:   pool,
+		},
+	}, true
+}
+
 func (gs *GameServer) NewLobbyHandler(c *gin.Context) {
 	var gameParams struct {
-		QuestionCount int `json:"questionCount"`
-		CountdownMs   int `json:"countdownMs"`
+		lobbySettingsParams
+		PlayerName string `json:"playerName"`
 	}
 	if err := c.ShouldBindJSON(&gameParams); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if _, err := game.ValidatePlayerName(gameParams.PlayerName); err != nil {
+		respondError(c, "Failed to create lobby", err)
+		return
+	}
+
+	settings, ok := gs.lobbySettings(c, gameParams.lobbySettingsParams, "Failed to create lobby")
+	if !ok {
 		return
 	}
 
+	// the player creating the lobby is the first one in it, which makes them its host.
 	sessionID := gs.generateSessionID()
-	lobbyID := gs.Lobbies.AddLobby(gameParams.QuestionCount, gameParams.CountdownMs, &game.Player{
+	lobbyID, player, err := gs.Lobbies.AddLobby(settings.QuestionCount, settings.Countdown, settings.QuestionTimeout, settings.LobbyOptions, &game.Player{
 		SessionID:         sessionID,
+		Name:              gameParams.PlayerName,
 		Score:             0,
 		QuestionsAnswered: []string{},
 	})
-	c.JSON(http.StatusOK, gin.H{"sessionId": sessionID, "lobbyId": lobbyID, "questionCount": gameParams.QuestionCount, "countdownMs": gameParams.CountdownMs})
+	if err != nil {
+		respondError(c, "Failed to create lobby", err)
+		return
+	}
+	summary := settings.Summary()
+	c.JSON(http.StatusOK, gin.H{"sessionId": sessionID, "playerId": player.ID, "playerName": player.Name, "lobbyId": lobbyID, "questionCount": summary.QuestionCount, "countdownMs": summary.CountdownMs, "questionTimeoutMs": summary.QuestionTimeoutMs, "questionFilter": summary.QuestionFilter, "scoring": summary.Scoring, "roundMode": summary.RoundMode, "answerRules": summary.AnswerRules, "readyCheck": summary.ReadyCheck, "joinRules": summary.JoinRules, "passwordProtected": summary.PasswordProtected, "questionBank": summary.QuestionBank})
 }
 
 func (gs *GameServer) JoinLobbyHandler(c *gin.Context) {
 	// Extracting the lobby ID from the URL path
 	lobbyId := c.Param("lobbyId")
 	if lobbyId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing lobby ID"})
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Missing lobby ID")
+		return
+	}
+	// the display name and password are optional and come in the query string so the join url stays share-able.
+	playerName := c.Query("name")
+	password := c.Query("password")
+	if _, err := game.ValidatePlayerName(playerName); err != nil {
+		respondError(c, "Failed to join lobby", err)
 		return
 	}
 
 	// Assuming you have a method to retrieve a lobby by its ID and another to add a player to a lobby
 	lobby, found := gs.Lobbies.GetLobby(lobbyId)
 	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Lobby not found"})
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby not found")
 		return
 	}
 
-	// AddPlayer is a method that adds a player to the specified lobby and returns an error if it fails
+	// Join checks the password, whether there is room and whether the game has started, and says which it was if one of them stops the player.
 	log.Printf("JoinLobbyHandler, put another player into lobby id: %s", lobbyId)
 	sessionId := gs.generateSessionID() //treat as a new player when joining a lobby. session is to identify the player within the lobby.
-	err := lobby.AddPlayer(sessionId)
+	player, err := lobby.Join(sessionId, playerName, password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join lobby: " + err.Error()})
+		respondError(c, "Failed to join lobby", err)
 		return
 	}
 
 	// Respond with a success message or other relevant information
-	c.JSON(http.StatusOK, gin.H{"message": "Joined lobby successfully", "lobbyId": lobbyId, "sessionId": sessionId})
+	c.JSON(http.StatusOK, gin.H{"message": "Joined lobby successfully", "lobbyId": lobbyId, "sessionId": sessionId, "playerId": player.ID, "playerName": player.Name, "spectator": player.Spectator})
+}
+
+func (gs *GameServer) LeaveLobbyHandler(c *gin.Context) {
+	var lobbyParams struct {
+		LobbyId   string `json:"lobbyId"`
+		SessionId string `json:"sessionId"`
+	}
+	if err := c.ShouldBindJSON(&lobbyParams); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	lobby, found := gs.Lobbies.GetLobby(lobbyParams.LobbyId)
+	if !found {
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby not found")
+		return
+	}
+
+	if err := lobby.RemovePlayer(lobbyParams.SessionId); err != nil {
+		respondError(c, "Failed to leave lobby", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Left lobby successfully", "lobbyId": lobbyParams.LobbyId})
 }
@@ -0,0 +1,11 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// MetricsHandler reports how event delivery to connected clients is going, e.g. whether slow clients are being dropped.
+func (gs *GameServer) MetricsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gs.Lobbies.Metrics())
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/ProlificLabs/captrivia/game"
+	"time"
+)
+
+// ProtocolVersion is the version of the command envelope that clients send over the websocket.
+// bump it whenever the shape of a command or its acknowledgement changes in a way old clients would trip over.
+const ProtocolVersion = 1
+
+// Command types that clients can send over the websocket.
+const (
+	CommandStart  = "start"
+	CommandAnswer = "answer"
+	CommandReady  = "ready"
+	CommandChat   = "chat"
+	CommandPing   = "ping"
+)
+
+// maxCommandBytes caps how large a single command from a client can be.
+const maxCommandBytes = 4096
+
+// ClientCommand is the envelope for everything a client sends over the websocket.
+// the requestId is chosen by the client and is echoed back in the acknowledgement so it can match them up.
+type ClientCommand struct {
+	Version   int             `json:"v"`
+	Type      string          `json:"type"`
+	RequestID string          `json:"requestId"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// CommandAck is sent back to the client that sent a command, and only to that client.
+type CommandAck struct {
+	Version   int         `json:"v"`
+	Type      string      `json:"type"` // always "ack"
+	RequestID string      `json:"requestId"`
+	OK        bool        `json:"ok"`
+	Error     string      `json:"error,omitempty"`
+	Code      ErrorCode   `json:"code,omitempty"` // set whenever Error is, see ErrorCode
+	Data      interface{} `json:"data,omitempty"`
+}
+
+type answerCommandData struct {
+	QuestionID string      `json:"questionId"`
+	Answer     game.Answer `json:"answer"` // see game.Answer for what it can be
+}
+
+type readyCommandData struct {
+	Ready bool `json:"ready"`
+}
+
+type chatCommandData struct {
+	Text string `json:"text"`
+}
+
+// handleCommand carries out a command from a player's websocket against their lobby, using the same lobby methods as the http handlers.
+func (gs *GameServer) handleCommand(lobby *game.GameLobby, player *game.Player, command ClientCommand) CommandAck {
+	data, err := gs.runCommand(lobby, player, command)
+	return newAck(command, data, err)
+}
+
+// handleSpectatorCommand answers commands from a spectator's websocket. they can check the connection with a ping, and that is all.
+func handleSpectatorCommand(command ClientCommand) CommandAck {
+	if command.Version == ProtocolVersion && command.Type == CommandPing {
+		return newAck(command, map[string]interface{}{"serverTime": time.Now().UnixMilli()}, nil)
+	}
+	return newAck(command, nil, fmt.Errorf("%w, they can only watch", game.ErrSpectator))
+}
+
+func newAck(command ClientCommand, data interface{}, err error) CommandAck {
+	ack := CommandAck{
+		Version:   ProtocolVersion,
+		Type:      "ack",
+		RequestID: command.RequestID,
+		OK:        err == nil,
+		Data:      data,
+	}
+	if err != nil {
+		ack.Error = err.Error()
+		ack.Code, _ = errorCode(err)
+	}
+	return ack
+}
+
+func (gs *GameServer) runCommand(lobby *game.GameLobby, player *game.Player, command ClientCommand) (interface{}, error) {
+	if command.Version != ProtocolVersion {
+		return nil, fmt.Errorf("%w %d, expected %d", errUnsupportedProtocol, command.Version, ProtocolVersion)
+	}
+
+	switch command.Type {
+	case CommandPing:
+		return map[string]interface{}{"serverTime": time.Now().UnixMilli()}, nil
+
+	case CommandStart:
+		if err := lobby.StartGameAs(player.SessionID, gs.lobbyQuestions(lobby)); err != nil {
+			return nil, err
+		}
+		settings := lobby.Settings()
+		return map[string]interface{}{"countdownMs": settings.Countdown, "questionCount": settings.QuestionCount}, nil
+
+	case CommandAnswer:
+		var answer answerCommandData
+		if err := decodeCommandData(command, &answer); err != nil {
+			return nil, err
+		}
+		err, result := lobby.Submit(player.SessionID, answer.QuestionID, answer.Answer)
+		return result, err
+
+	case CommandReady:
+		var ready readyCommandData
+		if err := decodeCommandData(command, &ready); err != nil {
+			return nil, err
+		}
+		if err := lobby.SetPlayerReady(player.SessionID, ready.Ready); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"ready": ready.Ready}, nil
+
+	case CommandChat:
+		var chat chatCommandData
+		if err := decodeCommandData(command, &chat); err != nil {
+			return nil, err
+		}
+		if err := lobby.SendChat(player.SessionID, chat.Text); err != nil {
+			return nil, err
+		}
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("%w: unknown command type %q", errInvalidCommand, command.Type)
+	}
+}
+
+func decodeCommandData(command ClientCommand, into interface{}) error {
+	if len(command.Data) == 0 {
+		return fmt.Errorf("%w: missing data for %s command", errInvalidCommand, command.Type)
+	}
+	if err := json.Unmarshal(command.Data, into); err != nil {
+		return fmt.Errorf("%w: invalid data for %s command: %v", errInvalidCommand, command.Type, err)
+	}
+	return nil
+}
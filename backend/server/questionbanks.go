@@ -0,0 +1,16 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// QuestionBanksHandler lists the question banks lobbies can be created with, the default one first.
+func (gs *GameServer) QuestionBanksHandler(c *gin.Context) {
+	banks := make([]gin.H, 0, len(gs.BankNames))
+	for _, name := range gs.BankNames {
+		questions, _ := gs.QuestionBank(name)
+		banks = append(banks, gin.H{"name": name, "questions": len(questions), "default": name == gs.DefaultBank})
+	}
+	c.JSON(http.StatusOK, gin.H{"questionBanks": banks})
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"github.com/ProlificLabs/captrivia/game"
+	"github.com/ProlificLabs/captrivia/questionbank"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// questionEditParams are the body of the admin requests that change a question.
+type questionEditParams struct {
+	Author   string        `json:"author"` // who made the change, kept with the revision
+	Question game.Question `json:"question"`
+}
+
+// editableBank works out which bank an admin question request is for, from its ?bank= (the default bank if absent),
+// along with that bank's questions as its source has them. it responds with the failure itself if there is a problem.
+func (gs *GameServer) editableBank(c *gin.Context) (string, []*game.Question, bool) {
+	if gs.editor == nil {
+		respondInvalid(c, http.StatusServiceUnavailable, CodeAuthoringDisabled, "Question authoring is not enabled")
+		return "", nil, false
+	}
+	bank := c.DefaultQuery("bank", gs.DefaultBank)
+	source, ok := gs.questionSource(bank)
+	if !ok {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid question bank: there is no bank named "+bank)
+		return "", nil, false
+	}
+	return bank, source, true
+}
+
+// ListQuestionsHandler lists a bank's questions as they currently stand, with ?retired=true to include the retired ones.
+func (gs *GameServer) ListQuestionsHandler(c *gin.Context) {
+	bank, source, ok := gs.editableBank(c)
+	if !ok {
+		return
+	}
+	questions := gs.editor.List(bank, source, c.Query("retired") == "true")
+	c.JSON(http.StatusOK, gin.H{"bank": bank, "questions": questions})
+}
+
+// CreateQuestionHandler adds a new question to a bank. new lobbies can be asked it straight away.
+func (gs *GameServer) CreateQuestionHandler(c *gin.Context) {
+	bank, source, ok := gs.editableBank(c)
+	if !ok {
+		return
+	}
+	var params questionEditParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	revision, warnings, err := gs.editor.Create(c.Request.Context(), bank, source, params.Question, params.Author)
+	if err != nil {
+		respondError(c, "Failed to create question", err)
+		return
+	}
+	gs.refreshQuestionBank(bank)
+	c.JSON(http.StatusCreated, gin.H{"revision": revision, "warnings": warnings})
+}
+
+// UpdateQuestionHandler saves a new revision of a question. the id in the path is the one that counts, it cannot be changed,
+// and neither can the type or options, see questionbank.Editor.Update.
+func (gs *GameServer) UpdateQuestionHandler(c *gin.Context) {
+	bank, source, ok := gs.editableBank(c)
+	if !ok {
+		return
+	}
+	var params questionEditParams
+	if err := c.ShouldBindJSON(&params); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if params.Question.ID != "" && params.Question.ID != c.Param("id") {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: a question's id cannot be changed")
+		return
+	}
+	params.Question.ID = c.Param("id")
+
+	revision, warnings, err := gs.editor.Update(c.Request.Context(), bank, source, params.Question, params.Author)
+	if err != nil {
+		respondError(c, "Failed to update question", err)
+		return
+	}
+	gs.refreshQuestionBank(bank)
+	c.JSON(http.StatusOK, gin.H{"revision": revision, "warnings": warnings})
+}
+
+// RetireQuestionHandler takes a question out of a bank for good. lobbies already playing with it carry on as they are.
+func (gs *GameServer) RetireQuestionHandler(c *gin.Context) {
+	bank, source, ok := gs.editableBank(c)
+	if !ok {
+		return
+	}
+	var params struct {
+		Author string `json:"author"`
+	}
+	// the body is optional, there is nothing else to say about retiring a question.
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&params); err != nil {
+			respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	revision, err := gs.editor.Retire(c.Request.Context(), bank, source, c.Param("id"), params.Author)
+	if err != nil {
+		respondError(c, "Failed to retire question", err)
+		return
+	}
+	gs.refreshQuestionBank(bank)
+	c.JSON(http.StatusOK, gin.H{"revision": revision})
+}
+
+// QuestionHistoryHandler lists every revision of a question made through the api, oldest first.
+func (gs *GameServer) QuestionHistoryHandler(c *gin.Context) {
+	bank, _, ok := gs.editableBank(c)
+	if !ok {
+		return
+	}
+	history := gs.editor.History(bank, c.Param("id"))
+	if history == nil {
+		history = []questionbank.Revision{}
+	}
+	c.JSON(http.StatusOK, gin.H{"bank": bank, "questionId": c.Param("id"), "revisions": history})
+}
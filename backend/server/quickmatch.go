@@ -0,0 +1,59 @@
+package server
+
+import (
+	"fmt"
+	"github.com/ProlificLabs/captrivia/game"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// defaultQuickMatchSize is how many players a quick match waits for when the player does not say.
+const defaultQuickMatchSize = 4
+
+// maxQuickMatchSize keeps quick match lobbies to a size that could actually fill up.
+const maxQuickMatchSize = 16
+
+// LobbiesHandler lists the public lobbies that are waiting for players, for a lobby browser.
+func (gs *GameServer) LobbiesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"lobbies": gs.Lobbies.PublicLobbies()})
+}
+
+// QuickMatchHandler puts the player into an open lobby with the settings they asked for, or a new one if there is none,
+// and the game starts as soon as the lobby has targetSize players in it.
+// the settings are the same as for creating a lobby, although the join rules and ready check are decided by the quick match.
+func (gs *GameServer) QuickMatchHandler(c *gin.Context) {
+	var matchParams struct {
+		lobbySettingsParams
+		PlayerName string `json:"playerName"`
+		TargetSize int    `json:"targetSize"` // defaultQuickMatchSize if absent
+	}
+	if err := c.ShouldBindJSON(&matchParams); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+	if matchParams.TargetSize == 0 {
+		matchParams.TargetSize = defaultQuickMatchSize
+	}
+	if matchParams.TargetSize < 1 || matchParams.TargetSize > maxQuickMatchSize {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("Invalid target size, it must be between 1 and %d", maxQuickMatchSize))
+		return
+	}
+	if _, err := game.ValidatePlayerName(matchParams.PlayerName); err != nil {
+		respondError(c, "Failed to quick match", err)
+		return
+	}
+
+	settings, ok := gs.lobbySettings(c, matchParams.lobbySettingsParams, "Failed to quick match")
+	if !ok {
+		return
+	}
+
+	sessionID := gs.generateSessionID()
+	lobby, player, err := gs.Lobbies.QuickMatch(settings, matchParams.TargetSize, sessionID, matchParams.PlayerName)
+	if err != nil {
+		respondError(c, "Failed to quick match", err)
+		return
+	}
+	summary := lobby.Summary()
+	c.JSON(http.StatusOK, gin.H{"lobbyId": lobby.ID, "sessionId": sessionID, "playerId": player.ID, "playerName": player.Name, "players": summary.Players, "targetSize": matchParams.TargetSize, "settings": summary.Settings})
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// ReadyHandler lets a player say whether they are ready to play, which everyone sees in the roster.
+// in a lobby that starts by itself this can be what starts the game.
+func (gs *GameServer) ReadyHandler(c *gin.Context) {
+	var readyParams struct {
+		LobbyId   string `json:"lobbyId"`
+		SessionId string `json:"sessionId"`
+		Ready     bool   `json:"ready"`
+	}
+	if err := c.ShouldBindJSON(&readyParams); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	lobby, found := gs.Lobbies.GetLobby(readyParams.LobbyId)
+	if !found {
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby not found")
+		return
+	}
+
+	if err := lobby.SetPlayerReady(readyParams.SessionId, readyParams.Ready); err != nil {
+		respondError(c, "Failed to set ready", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ready": readyParams.Ready})
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// RematchHandler lets the host take an ended lobby back to waiting for another round with the same players and settings.
+func (gs *GameServer) RematchHandler(c *gin.Context) {
+	var lobbyParams struct {
+		LobbyId   string `json:"lobbyId"`
+		SessionId string `json:"sessionId"`
+	}
+	if err := c.ShouldBindJSON(&lobbyParams); err != nil {
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body: "+err.Error())
+		return
+	}
+
+	lobby, found := gs.Lobbies.GetLobby(lobbyParams.LobbyId)
+	if !found {
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby not found")
+		return
+	}
+
+	if err := lobby.Rematch(lobbyParams.SessionId); err != nil {
+		respondError(c, "Failed to rematch", err)
+		return
+	}
+	rounds, standings := lobby.History()
+	c.JSON(http.StatusOK, gin.H{"message": "Back to waiting for the next round", "round": len(rounds) + 1, "standings": standings})
+}
+
+// HistoryHandler returns the rounds a lobby has played and everyone's running totals over them.
+func (gs *GameServer) HistoryHandler(c *gin.Context) {
+	lobby, found := gs.Lobbies.GetLobby(c.Param("lobbyId"))
+	if !found {
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby not found")
+		return
+	}
+	rounds, standings := lobby.History()
+	c.JSON(http.StatusOK, gin.H{"rounds": rounds, "standings": standings})
+}
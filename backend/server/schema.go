@@ -0,0 +1,17 @@
+package server
+
+import (
+	_ "embed"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// eventsSchema describes every message that goes over the game events websocket, for clients that want to generate bindings.
+// keep it in step with game/events.go and protocol.go.
+//
+//go:embed schema/events.schema.json
+var eventsSchema []byte
+
+func (gs *GameServer) EventsSchemaHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", eventsSchema)
+}
@@ -3,21 +3,126 @@ package server
 import (
 	"crypto/rand"
 	"fmt"
+	"github.com/ProlificLabs/captrivia/analytics"
 	"github.com/ProlificLabs/captrivia/game"
+	"github.com/ProlificLabs/captrivia/questionbank"
+	"sync"
 )
 
 type GameServer struct {
-	Questions []*game.Question
+	// the banks' questions get swapped out whenever they are reloaded, so they are only got at through QuestionBank,
+	// which keeps that safe. the names do not change.
+	banksMutex  sync.RWMutex
+	banks       map[string][]*game.Question // every question bank lobbies can choose from by name, the default one included
+	sources     map[string][]*game.Question // the banks' questions as their sources have them, before any edits made through the api
+	editor      *questionbank.Editor        // nil when questions cannot be edited through the api
+	BankNames   []string                    // the banks' names, the default one first
+	DefaultBank string
 	//Sessions  *SessionStore
-	Lobbies *game.Lobbies
+	Lobbies    *game.Lobbies
+	Stats      analytics.Querier      // nil when analytics are not being recorded
+	Reloader   *questionbank.Reloader // nil when the question banks are not being reloaded
+	AdminToken string                 // needed for the admin endpoints, which are off when it is blank
 }
 
-func NewGameServer(questions []*game.Question, lobbies *game.Lobbies) *GameServer {
-	return &GameServer{
-		Questions: questions,
+// NewGameServer serves the given question banks, the first of which is the default for lobbies that do not pick one.
+func NewGameServer(banks []questionbank.Bank, lobbies *game.Lobbies) *GameServer {
+	gs := &GameServer{
+		banks:   make(map[string][]*game.Question, len(banks)),
+		sources: make(map[string][]*game.Question, len(banks)),
 		//Sessions:  store,
 		Lobbies: lobbies,
 	}
+	for _, bank := range banks {
+		gs.banks[bank.Name] = bank.Questions
+		gs.sources[bank.Name] = bank.Questions
+		gs.BankNames = append(gs.BankNames, bank.Name)
+	}
+	if len(banks) > 0 {
+		gs.DefaultBank = banks[0].Name
+	}
+	return gs
+}
+
+// QuestionBank returns the named bank's questions as they are now, false if there is no such bank. a blank name is the default bank.
+// the slice is never changed once handed out, a reload swaps in a new one, so a lobby that has started keeps the questions it got.
+func (gs *GameServer) QuestionBank(name string) ([]*game.Question, bool) {
+	if name == "" {
+		name = gs.DefaultBank
+	}
+	gs.banksMutex.RLock()
+	defer gs.banksMutex.RUnlock()
+	questions, ok := gs.banks[name]
+	return questions, ok
+}
+
+// SwapQuestionBank replaces the named bank's questions, as loaded from its source, for lobbies to use from the next time they draw any.
+// any edits made through the api still apply on top of them.
+func (gs *GameServer) SwapQuestionBank(name string, questions []*game.Question) {
+	gs.banksMutex.Lock()
+	defer gs.banksMutex.Unlock()
+	gs.sources[name] = questions
+	gs.applyEdits(name)
+}
+
+// SetEditor lets questions be edited through the api, applying the edits already made to every bank.
+func (gs *GameServer) SetEditor(editor *questionbank.Editor) {
+	gs.banksMutex.Lock()
+	defer gs.banksMutex.Unlock()
+	gs.editor = editor
+	for _, name := range gs.BankNames {
+		gs.applyEdits(name)
+	}
+}
+
+// questionSource returns the named bank's questions as its source has them, false if there is no such bank.
+func (gs *GameServer) questionSource(name string) ([]*game.Question, bool) {
+	gs.banksMutex.RLock()
+	defer gs.banksMutex.RUnlock()
+	questions, ok := gs.sources[name]
+	return questions, ok
+}
+
+// refreshQuestionBank brings the named bank up to date after an edit.
+func (gs *GameServer) refreshQuestionBank(name string) {
+	gs.banksMutex.Lock()
+	defer gs.banksMutex.Unlock()
+	gs.applyEdits(name)
+}
+
+// applyEdits works out the named bank's questions from its source and the edits. must be called while holding the banks mutex for writing.
+func (gs *GameServer) applyEdits(name string) {
+	if gs.editor == nil {
+		gs.banks[name] = gs.sources[name]
+		return
+	}
+	gs.banks[name] = gs.editor.Apply(name, gs.sources[name])
+}
+
+// Questions is the default bank, which is where lobbies that did not pick a question bank get their questions.
+func (gs *GameServer) Questions() []*game.Question {
+	questions, _ := gs.QuestionBank("")
+	return questions
+}
+
+// bankPool is where lobbies playing with the named bank get their questions, false if there is no such bank.
+// a blank name is the default bank.
+func (gs *GameServer) bankPool(name string) (func() []*game.Question, bool) {
+	if _, ok := gs.QuestionBank(name); !ok {
+		return nil, false
+	}
+	return func() []*game.Question {
+		questions, _ := gs.QuestionBank(name)
+		return questions
+	}, true
+}
+
+// lobbyQuestions is the pool a lobby's game should be drawn from, going by the question bank it picked.
+func (gs *GameServer) lobbyQuestions(lobby *game.GameLobby) []*game.Question {
+	if pool := lobby.Settings().QuestionPool; pool != nil {
+		return pool()
+	}
+	return gs.Questions()
 }
 
 func (gs *GameServer) generateSessionID() string {
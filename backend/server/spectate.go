@@ -0,0 +1,58 @@
+package server
+
+import (
+	"github.com/ProlificLabs/captrivia/game"
+	"github.com/gin-gonic/gin"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// SpectateHandler streams a lobby's events to someone watching without playing, like a streamer or a coach.
+// it works like WsHandler except there is no session, and the only command a spectator can send is a ping.
+func (gs *GameServer) SpectateHandler(c *gin.Context) {
+	lobbyId := c.Param("lobbyId")
+	lobby, found := gs.Lobbies.GetLobby(lobbyId)
+	if !found {
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby doesnt exist")
+		return
+	}
+
+	// subscribe before looking at the event log, so nothing published in between can fall through the gap.
+	subscription := lobby.Spectate()
+	defer lobby.StopSpectating(subscription)
+
+	var missed []*game.Event
+	var snapshot *game.Event
+	since, resuming := c.GetQuery("since")
+	if resuming {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid since: "+since)
+			return
+		}
+		missed, snapshot = lobby.ResumeSpectating(seq)
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		respondInvalid(c, http.StatusInternalServerError, CodeInternal, "Failed to establish WebSocket connection")
+		return
+	}
+	defer func() {
+		log.Println("close the spectator websocket conn")
+		conn.Close()
+	}()
+
+	replayedThrough, ok := replay(conn, resuming, missed, snapshot)
+	if !ok {
+		return
+	}
+
+	acks := make(chan CommandAck, 8)
+	readerDone := make(chan struct{})
+	writerDone := make(chan struct{})
+	defer close(writerDone)
+	go readCommands(conn, handleSpectatorCommand, acks, readerDone, writerDone)
+	writeEvents(conn, subscription, replayedThrough, acks, readerDone)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"errors"
+	"github.com/ProlificLabs/captrivia/analytics"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// QuestionStatsEntry is analytics.QuestionStats with the question itself filled in, so the content team can read it as is.
+type QuestionStatsEntry struct {
+	analytics.QuestionStats
+	QuestionText              string `json:"questionText,omitempty"`
+	MostCommonWrongAnswerText string `json:"mostCommonWrongAnswerText,omitempty"`
+}
+
+// QuestionStatsHandler reports how players do on each question, hardest first.
+// questions that nobody gets right, or that keep drawing the same wrong answer, are the ones worth a second look.
+func (gs *GameServer) QuestionStatsHandler(c *gin.Context) {
+	if gs.Stats == nil {
+		respondInvalid(c, http.StatusServiceUnavailable, CodeAnalyticsDisabled, "Analytics are not enabled")
+		return
+	}
+	stats, err := gs.Stats.QuestionStats(c.Request.Context())
+	if err != nil {
+		respondInvalid(c, http.StatusInternalServerError, CodeInternal, "Failed to load question stats")
+		return
+	}
+
+	// look through every bank, the default one last so that it wins if two banks share an id.
+	questionText := make(map[string][]string)
+	for i := len(gs.BankNames) - 1; i >= 0; i-- {
+		bank, _ := gs.QuestionBank(gs.BankNames[i])
+		for _, question := range bank {
+			questionText[question.ID] = append([]string{question.QuestionText}, question.Options...)
+		}
+	}
+
+	entries := make([]QuestionStatsEntry, 0, len(stats))
+	for _, s := range stats {
+		entry := QuestionStatsEntry{QuestionStats: s}
+		// questions may have been removed from the pool since they were answered, in which case we only have the id.
+		if text, ok := questionText[s.QuestionID]; ok {
+			entry.QuestionText = text[0]
+			if s.MostCommonWrongAnswer != nil && *s.MostCommonWrongAnswer >= 0 && *s.MostCommonWrongAnswer < len(text)-1 {
+				entry.MostCommonWrongAnswerText = text[*s.MostCommonWrongAnswer+1]
+			}
+		}
+		entries = append(entries, entry)
+	}
+	c.JSON(http.StatusOK, gin.H{"questions": entries})
+}
+
+// PlayerStatsHandler reports a player's accuracy and win rate over the games played in one lobby.
+// player ids are only good for the lobby they were given out in, so the lobby is part of the path.
+func (gs *GameServer) PlayerStatsHandler(c *gin.Context) {
+	if gs.Stats == nil {
+		respondInvalid(c, http.StatusServiceUnavailable, CodeAnalyticsDisabled, "Analytics are not enabled")
+		return
+	}
+	stats, err := gs.Stats.PlayerStats(c.Request.Context(), c.Param("lobbyId"), c.Param("id"))
+	if errors.Is(err, analytics.ErrNoPlayerStats) {
+		respondInvalid(c, http.StatusNotFound, CodePlayerNotFound, "Player not found")
+		return
+	}
+	if err != nil {
+		respondInvalid(c, http.StatusInternalServerError, CodeInternal, "Failed to load player stats")
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
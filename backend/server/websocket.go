@@ -1,10 +1,14 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
+	"github.com/ProlificLabs/captrivia/game"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"log"
 	"net/http"
+	"strconv"
 )
 
 var upgrader = websocket.Upgrader{
@@ -20,29 +24,55 @@ func (gs *GameServer) WsHandler(c *gin.Context) {
 	// Extracting the lobby ID and player sessionId from the URL path
 	lobbyId := c.Param("lobbyId")
 	if lobbyId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing lobby ID"})
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Missing lobby ID")
 		return
 	}
 	sessionId := c.Param("sessionId")
 	if sessionId == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing session ID"})
+		respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Missing session ID")
 		return
 	}
 
 	lobby, found := gs.Lobbies.GetLobby(lobbyId)
 	if !found {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Lobby doesnt exist"})
+		respondInvalid(c, http.StatusNotFound, CodeLobbyNotFound, "Lobby doesnt exist")
 		return
 	}
 	player, _ := lobby.GetPlayer(sessionId) //TODO decide and make consistent the return style of these getters. idk probably dont actually need to send and error
 	if player == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Player not found"})
+		respondInvalid(c, http.StatusNotFound, CodePlayerNotFound, "Player not found")
 		return
 	}
 
+	// subscribe before looking at the event log, so nothing published in between can fall through the gap.
+	subscription, err := lobby.Connect(sessionId)
+	if err != nil {
+		respondError(c, "Failed to connect", err)
+		return
+	}
+	// the host's websocket going away is how the lobby knows to find a new host.
+	defer lobby.Disconnect(sessionId)
+
+	// a client that is reconnecting tells us the seq of the last event it saw, so we can replay what it missed.
+	var missed []*game.Event
+	var snapshot *game.Event
+	since, resuming := c.GetQuery("since")
+	if resuming {
+		seq, err := strconv.ParseUint(since, 10, 64)
+		if err != nil {
+			respondInvalid(c, http.StatusBadRequest, CodeInvalidRequest, "Invalid since: "+since)
+			return
+		}
+		missed, snapshot, err = lobby.Resume(sessionId, seq)
+		if err != nil {
+			respondError(c, "Failed to resume", err)
+			return
+		}
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to establish WebSocket connection"})
+		respondInvalid(c, http.StatusInternalServerError, CodeInternal, "Failed to establish WebSocket connection")
 		return
 	}
 
@@ -52,14 +82,53 @@ func (gs *GameServer) WsHandler(c *gin.Context) {
 	}
 	defer cleanup()
 
+	replayedThrough, ok := replay(conn, resuming, missed, snapshot)
+	if !ok {
+		return
+	}
+
+	// the connection only allows one writer at a time, so acknowledgements for commands read off the socket
+	// are handed to this goroutine to write out alongside the lobby messages.
+	acks := make(chan CommandAck, 8)
+	readerDone := make(chan struct{})
+	writerDone := make(chan struct{})
+	defer close(writerDone)
+	go readCommands(conn, func(command ClientCommand) CommandAck {
+		return gs.handleCommand(lobby, player, command)
+	}, acks, readerDone, writerDone)
+	writeEvents(conn, subscription, replayedThrough, acks, readerDone)
+}
+
+// replay writes out the events a resuming client missed and the snapshot after them, returning the seq it replayed up to.
+// anything replayed may also still be sitting in the subscription, so writeEvents skips those when they come through.
+func replay(conn *websocket.Conn, resuming bool, missed []*game.Event, snapshot *game.Event) (uint64, bool) {
+	if !resuming {
+		return 0, true
+	}
+	for _, event := range append(missed, snapshot) {
+		if err := conn.WriteJSON(event); err != nil {
+			log.Println("Failed to replay", event.Type, event.Seq)
+			log.Println("Write error:", err)
+			return 0, false
+		}
+	}
+	return snapshot.Seq, true
+}
+
+// writeEvents is the only writer to the connection, sending the lobby's events and the acknowledgements for commands
+// until the subscription ends, the reader gives up or a write fails.
+func writeEvents(conn *websocket.Conn, subscription *game.Subscription, replayedThrough uint64, acks <-chan CommandAck, readerDone <-chan struct{}) {
 	for {
 		select {
-		case message, ok := <-player.MessageChannel:
+		case message, ok := <-subscription.Events():
 			if !ok {
-				// The channel was closed; exit the loop
-				log.Println("Message channel closed.")
+				// The subscription ended (player left, lobby was cleaned up, or we fell too far behind); exit the loop
+				log.Println("Subscription closed.")
 				return
 			}
+			if message.Seq <= replayedThrough {
+				continue
+			}
 			log.Printf("sending a message like this: %+v", message)
 			if err := conn.WriteJSON(message); err != nil {
 				// Handle error: failed to send message
@@ -67,6 +136,48 @@ func (gs *GameServer) WsHandler(c *gin.Context) {
 				log.Println("Write error:", err)
 				return
 			}
+		case ack := <-acks:
+			if err := conn.WriteJSON(ack); err != nil {
+				log.Println("Failed to send ack for request", ack.RequestID)
+				log.Println("Write error:", err)
+				return
+			}
+		case <-readerDone:
+			// client went away (or sent us garbage), nothing left to do.
+			return
+		}
+	}
+}
+
+// readCommands reads commands from the client until the connection fails, handing back handle's acknowledgement for each one.
+func readCommands(conn *websocket.Conn, handle func(ClientCommand) CommandAck, acks chan<- CommandAck, done chan<- struct{}, writerDone <-chan struct{}) {
+	defer close(done)
+	conn.SetReadLimit(maxCommandBytes)
+	sendAck := func(ack CommandAck) bool {
+		select {
+		case acks <- ack:
+			return true
+		case <-writerDone:
+			return false
+		}
+	}
+	for {
+		var command ClientCommand
+		if err := conn.ReadJSON(&command); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				// the frame was read fine, it just wasn't a command we understand. tell the client and carry on.
+				if !sendAck(CommandAck{Version: ProtocolVersion, Type: "ack", Error: "invalid command: " + err.Error(), Code: CodeInvalidRequest}) {
+					return
+				}
+				continue
+			}
+			log.Println("Read error:", err)
+			return
+		}
+		if !sendAck(handle(command)) {
+			return
 		}
 	}
 }
@@ -15,6 +15,8 @@ export const AppContext = createContext();
 function App() {
   const [lobbySession, setLobbySession] = useState(null);
   const [playerSession, setPlayerSession] = useState(null);
+  const [playerId, setPlayerId] = useState(null);
+  const [playerName, setPlayerName] = useState(localStorage.getItem("playerName") || "");
   const [questions, setQuestions] = useState([]);
   const [currentQuestionAnswered, setCurrentQuestionAnswered] = useState(false);
   const [gameStarted, setGameStarted] = useState(false);
@@ -29,10 +31,11 @@ function App() {
   const [countdownSeconds, setCountdownSeconds] = useState(5);
   const [countdownRunning, setCountdownRunning] = useState(false);
   const [countdownRemainingMs, setCountdownRemainingMs] = useState(0);
+  const [isHost, setIsHost] = useState(false);
   const hasJoinedLobby = useRef(false); // using this to very aggressively prevent double execution of lobby-joining since the server is responsible for generating and adding the new session, doing it more than once is bad.
 
-  useWebsocketEventListener(API_BASE, playerSession, lobbySession, setGameStarted, setCountdownRunning, setCountdownRemainingMs, setQuestions, setGameEnded, setWinnerMessage, setWinningScore, setError, setLoading, setNoPointsAwarded, setCurrentQuestionAnswered)
-  useJoinLobby(API_BASE, setLobbySession, setPlayerSession, setError, setLoading, hasJoinedLobby);
+  useWebsocketEventListener(API_BASE, playerSession, playerId, lobbySession, setGameStarted, setCountdownRunning, setCountdownRemainingMs, setQuestions, setGameEnded, setWinnerMessage, setWinningScore, setError, setLoading, setNoPointsAwarded, setCurrentQuestionAnswered, setIsHost)
+  useJoinLobby(API_BASE, playerName, setLobbySession, setPlayerSession, setPlayerId, setError, setLoading, hasJoinedLobby);
 
   if (error) return <div className="error">Error: {error}</div>;
   if (loading) return <div className="loading">Loading...</div>;
@@ -46,7 +49,10 @@ function App() {
                 setQuestionCount={setQuestionCount}
                 countdownSeconds={countdownSeconds}
                 setCountdownSeconds={setCountdownSeconds}
+                playerName={playerName}
+                setPlayerName={setPlayerName}
                 setPlayerSession={setPlayerSession}
+                setPlayerId={setPlayerId}
                 setLobbySession={setLobbySession}
                 setError={setError}
                 setLoading={setLoading}
@@ -62,6 +68,7 @@ function App() {
             <StartGame
                 lobbySession={lobbySession}
                 playerSession={playerSession}
+                isHost={isHost}
                 setLoading={setLoading}
                 setError={setError}
             />
@@ -1,7 +1,7 @@
 import React, {useContext} from 'react';
 import { AppContext } from '../App';
 
-const LobbyCreation = ({ questionCount, setQuestionCount, countdownSeconds, setCountdownSeconds, setPlayerSession, setLobbySession, setError, setLoading }) => {
+const LobbyCreation = ({ questionCount, setQuestionCount, countdownSeconds, setCountdownSeconds, playerName, setPlayerName, setPlayerSession, setPlayerId, setLobbySession, setError, setLoading }) => {
     const { API_BASE } = useContext(AppContext);
 
     const createNewLobby = async () => {
@@ -15,6 +15,7 @@ const LobbyCreation = ({ questionCount, setQuestionCount, countdownSeconds, setC
                     body: JSON.stringify({
                         questionCount: questionCount,
                         countdownMs: countdownSeconds * 1000, //just using seconds for the ui
+                        playerName: playerName,
                     }),
                 });
 
@@ -24,6 +25,7 @@ const LobbyCreation = ({ questionCount, setQuestionCount, countdownSeconds, setC
                     // Assuming the response includes the lobbySession or playerSession identifier
                     // setLobbySession(data.lobbyId); // Update this line based on your actual response structure
                     setPlayerSession(data.sessionId);
+                    setPlayerId(data.playerId);
                     setLobbySession(data.lobbyId);
                     // setGameParams(data);
                     // Additional logic to handle successful lobby creation
@@ -43,6 +45,15 @@ const LobbyCreation = ({ questionCount, setQuestionCount, countdownSeconds, setC
         <div className="create-lobby-container">
             <h2>Create New Lobby</h2>
             <div className="lobby-settings">
+                <div className="setting">
+                    <label>
+                        Your Name:
+                        <input type="text" value={playerName} maxLength={24} onChange={(e) => {
+                            setPlayerName(e.target.value);
+                            localStorage.setItem("playerName", e.target.value); // remembered for joining other lobbies by link too
+                        }} />
+                    </label>
+                </div>
                 <div className="setting">
                     <label>
                         Number of Questions:
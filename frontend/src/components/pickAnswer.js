@@ -26,15 +26,14 @@ const PickAnswer = ({ questions, score, lobbySession, playerSession, setScore, s
             });
             const data = await res.json();
             console.log("got this back from submit answer:", data)
-            if (res.ok) {
-                if (data.points) {
-                    //TODO points will tell us if we got the question right or not ... do something fancy if so
-                    setScore(data.score); // Update score from server's response
-                } else {
-                    //we didnt get points so we must have answered it wrong.
+            // refusals like answering a question that has already moved on come back with an error code, they are not failures to submit.
+            if (res.ok || data.code) {
+                if (data.accepted) {
+                    setScore(data.score); // Update score from server's response, which can go down with a wrong answer penalty
+                }
+                if (!data.correct) {
+                    //we didnt get it right, or it was refused, or (in everyone answers mode) we wont know until the reveal.
                     //in theory its possible we raced to submit the correct answer but werent processed first by the server
-                    //it might be a little cleaner to include whether the answer was correct or not in the server response as opposed to just whether points were awarded
-                    //but this should be good enough for now.
                     setNoPointsAwarded(true)
                 }
             } else {
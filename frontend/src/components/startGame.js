@@ -10,7 +10,7 @@ const copyToClipboard = (text) => {
     });
 };
 
-const StartGame = ({ lobbySession, playerSession, setLoading, setError }) => {
+const StartGame = ({ lobbySession, playerSession, isHost, setLoading, setError }) => {
     const { API_BASE } = useContext(AppContext);
 
     const startGame = async () => {
@@ -40,8 +40,12 @@ const StartGame = ({ lobbySession, playerSession, setLoading, setError }) => {
 
     return (
         <div>
-            {/* Section for starting a game within an existing lobby */}
-            <button onClick={startGame}>Start Game</button>
+            {/* Section for starting a game within an existing lobby, which only the host can do */}
+            {isHost ? (
+                <button onClick={startGame}>Start Game</button>
+            ) : (
+                <p>Waiting for the host to start the game.</p>
+            )}
             <div>
                 <p>Share this lobby link:</p>
                 <input
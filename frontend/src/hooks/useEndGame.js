@@ -1,5 +1,5 @@
 const useEndGame = () => {
-    const endGame = async (API_BASE, lobbySession, playerSession, setGameEnded, setWinnerMessage, setWinningScore, setError, setLoading) => {
+    const endGame = async (API_BASE, lobbySession, playerId, setGameEnded, setWinnerMessage, setWinningScore, setError, setLoading) => {
         setLoading(true);
         try {
             const res = await fetch(`${API_BASE}/game/status/${lobbySession}`, {
@@ -13,11 +13,11 @@ const useEndGame = () => {
             console.log("game status after end: ", data)
 
             // Check if the current user is a winner
-            const isWinner = data.winners.includes(playerSession);
+            const isWinner = data.winners.some(winner => winner.id === playerId);
             const you = "You"
 
-            // Replace the current user's session ID with "you" and reorder to put "you" first if present
-            const winnersFormatted = data.winners.map(winner => winner === playerSession ? you : winner);
+            // Replace the current user's name with "you" and reorder to put "you" first if present
+            const winnersFormatted = data.winners.map(winner => winner.id === playerId ? you : winner.name);
             if (isWinner && winnersFormatted.length > 1) {
                 const index = winnersFormatted.indexOf(you);
                 winnersFormatted.splice(index, 1); // Remove "you"
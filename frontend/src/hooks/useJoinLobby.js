@@ -1,7 +1,7 @@
 import { useEffect } from 'react';
 import { useParams } from 'react-router-dom';
 
-const useJoinLobby = (API_BASE, setLobbySession, setPlayerSession, setError, setLoading, hasJoinedLobby) => {
+const useJoinLobby = (API_BASE, playerName, setLobbySession, setPlayerSession, setPlayerId, setError, setLoading, hasJoinedLobby) => {
 
     // Use useParams hook to extract lobby UUID from the URL
     const { lobbyUuid } = useParams(); // Extract lobbyUuid from URL
@@ -16,7 +16,7 @@ const useJoinLobby = (API_BASE, setLobbySession, setPlayerSession, setError, set
                 const joinLobby = async () => {
                     setLoading(true);
                     try {
-                        const response = await fetch(`${API_BASE}/game/joinlobby/${lobbyUuid}`, {
+                        const response = await fetch(`${API_BASE}/game/joinlobby/${lobbyUuid}?name=${encodeURIComponent(playerName)}`, {
                             method: "GET",
                             headers: {
                                 "Content-Type": "application/json",
@@ -30,6 +30,7 @@ const useJoinLobby = (API_BASE, setLobbySession, setPlayerSession, setError, set
                         // Handle successful lobby join
                         console.log("got data from attempt to joinlobby", data)
                         setPlayerSession(data.sessionId)
+                        setPlayerId(data.playerId)
                         console.log("player joined game with session id", data.sessionId)
                     } catch (error) {
                         setError(error.message);
@@ -42,7 +43,7 @@ const useJoinLobby = (API_BASE, setLobbySession, setPlayerSession, setError, set
                 joinLobby();
             }
         }
-    }, [lobbyUuid, setLobbySession, setPlayerSession, setError, setLoading, API_BASE, hasJoinedLobby]);
+    }, [lobbyUuid, playerName, setLobbySession, setPlayerSession, setPlayerId, setError, setLoading, API_BASE, hasJoinedLobby]);
 };
 
 export default useJoinLobby;
@@ -1,25 +1,46 @@
 import { useEffect } from 'react';
 import useEndGame from "./useEndGame";
 
-const useWebsocketEventListener = (API_BASE, playerSession, lobbySession, setGameStarted, setCountdownRunning, setCountdownRemainingMs, setQuestions, setGameEnded, setWinnerMessage, setWinningScore, setError, setLoading, setNoPointsAwarded, setCurrentQuestionAnswered) => {
+const useWebsocketEventListener = (API_BASE, playerSession, playerId, lobbySession, setGameStarted, setCountdownRunning, setCountdownRemainingMs, setQuestions, setGameEnded, setWinnerMessage, setWinningScore, setError, setLoading, setNoPointsAwarded, setCurrentQuestionAnswered, setIsHost) => {
     // Effect for WebSocket setup
     const endGame = useEndGame();
 
     useEffect(() => {
         if (!playerSession || !lobbySession) return; // Only connect WebSocket after lobby is waiting
         const API_WS = API_BASE.replace(/^http/, "ws");
-        const websocket = new WebSocket(`${API_WS}/game/events/${lobbySession}/${playerSession}`);
+        let websocket = null;
+        let lastSeq = null; // seq of the last event we handled, so a reconnect can ask for whatever it missed.
+        let closing = false;
 
-        websocket.onopen = () => {
-            console.log('WebSocket Connected');
+        const connect = () => {
+            const since = lastSeq === null ? "" : `?since=${lastSeq}`;
+            websocket = new WebSocket(`${API_WS}/game/events/${lobbySession}/${playerSession}${since}`);
+            websocket.onopen = () => {
+                console.log('WebSocket Connected');
+            };
+            websocket.onmessage = onMessage;
+            websocket.onclose = () => {
+                if (!closing) {
+                    console.log('WebSocket dropped, reconnecting ...');
+                    setTimeout(connect, 1000);
+                }
+            };
         };
 
-        websocket.onmessage = async (event) => {
+        const onMessage = async (event) => {
             // setServerMessage(event.data);
-            const data = JSON.parse(event.data);
-            console.log("got message from server", data)
-            // Handle different types of messages
-            if (data.question) {
+            const message = JSON.parse(event.data);
+            console.log("got message from server", message)
+            if (message.type !== "snapshot") {
+                if (lastSeq !== null && message.seq <= lastSeq) return; // already handled this one
+                lastSeq = message.seq;
+            }
+            // Handle different types of messages, see the server's /game/schema/events for what each one carries.
+            const data = message.data;
+            if (message.type === "snapshot") {
+                // we only get these after reconnecting. any question we missed was replayed just before this so there is nothing more to do.
+                console.log("caught up with the server", data)
+            } else if (message.type === "question") {
                 console.log("received question")
                 setGameStarted(true)
                 setCountdownRunning(false)
@@ -28,25 +49,28 @@ const useWebsocketEventListener = (API_BASE, playerSession, lobbySession, setGam
                 setQuestions(prev => [...prev, data.question]);
                 console.log("saying we have not answered the current question")
                 setCurrentQuestionAnswered(false)
-            } else if (data.countdownMs) {
+            } else if (message.type === "roster") {
+                // only the host can start the game, and who that is can change as players come and go.
+                setIsHost(data.players.some(player => player.id === playerId && player.host))
+            } else if (message.type === "countdown") {
                 console.log("show countdown ticker for this many ms:", data.countdownMs)
                 setCountdownRunning(true)
                 setCountdownRemainingMs(data.countdownMs)
-            } else if (data.gameOver) {
+            } else if (message.type === "gameOver") {
                 try {
                     //switch to the gameOver screen with winner info.
-                    await endGame(API_BASE, lobbySession, playerSession, setGameEnded, setWinnerMessage, setWinningScore, setError, setLoading)
+                    await endGame(API_BASE, lobbySession, playerId, setGameEnded, setWinnerMessage, setWinningScore, setError, setLoading)
                 } catch (error) {
                     console.error("Failed to end the game:", error);
                 }
-            } else if (data.content) {
-                //websocket debugging
-                console.log("spam data", data)
             }
         };
 
+        connect();
+
         return () => {
             console.log('Closing WebSocket...');
+            closing = true;
             websocket.close();
         };
     // TODO learn about the useCallback stuff that could maybe solve this lint